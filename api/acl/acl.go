@@ -0,0 +1,119 @@
+package acl
+
+import (
+	"fmt"
+	"gsm/models"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ACL answers whether a user may perform an action on a container.
+// Roles with the matching global permission may act on every container, other users need an explicit grant.
+type ACL struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *ACL {
+	return &ACL{db: db}
+}
+
+// IsValidAction reports whether action is one of models.ContainerActions
+func IsValidAction(action models.ContainerAction) bool {
+	for _, a := range models.ContainerActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *ACL) Allowed(email string, role models.UserRole, container string, action models.ContainerAction) bool {
+	if role.Can(action.Permission()) {
+		return true
+	}
+
+	var count int64
+	a.db.Model(&models.ContainerPermission{}).
+		Where("container = ? AND email = ? AND action = ?", container, email, action).
+		Count(&count)
+	return count > 0
+}
+
+// Actions returns the actions the user may perform on the container
+func (a *ACL) Actions(email string, role models.UserRole, container string) ([]models.ContainerAction, error) {
+	var granted []models.ContainerAction
+	err := a.db.Model(&models.ContainerPermission{}).
+		Where("container = ? AND email = ?", container, email).
+		Pluck("action", &granted).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch permissions: %v", err)
+	}
+
+	actions := []models.ContainerAction{}
+	for _, action := range models.ContainerActions {
+		if role.Can(action.Permission()) || slices.Contains(granted, action) {
+			actions = append(actions, action)
+		}
+	}
+	return actions, nil
+}
+
+// Containers returns the names of the containers the user may perform action on, all is true when the role allows it everywhere
+func (a *ACL) Containers(email string, role models.UserRole, action models.ContainerAction) (names map[string]bool, all bool, err error) {
+	if role.Can(action.Permission()) {
+		return nil, true, nil
+	}
+
+	var containers []string
+	err = a.db.Model(&models.ContainerPermission{}).
+		Where("email = ? AND action = ?", email, action).
+		Pluck("container", &containers).Error
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch permissions: %v", err)
+	}
+
+	names = make(map[string]bool, len(containers))
+	for _, container := range containers {
+		names[container] = true
+	}
+	return names, false, nil
+}
+
+// List returns all grants on the container
+func (a *ACL) List(container string) ([]models.ContainerPermission, error) {
+	var permissions []models.ContainerPermission
+	if err := a.db.Where("container = ?", container).Order("email, action").Find(&permissions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch permissions: %v", err)
+	}
+	return permissions, nil
+}
+
+// Grant gives the user the actions on the container, existing grants are kept
+func (a *ACL) Grant(container, email string, actions []models.ContainerAction, grantedBy string) error {
+	for _, action := range actions {
+		permission := models.ContainerPermission{
+			Container: container,
+			Email:     email,
+			Action:    action,
+			GrantedBy: grantedBy,
+		}
+		if err := a.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&permission).Error; err != nil {
+			return fmt.Errorf("failed to grant %s: %v", action, err)
+		}
+	}
+	return nil
+}
+
+// Revoke removes the actions from the user, all actions when none are given
+func (a *ACL) Revoke(container, email string, actions []models.ContainerAction) error {
+	query := a.db.Unscoped().Where("container = ? AND email = ?", container, email)
+	if len(actions) > 0 {
+		query = query.Where("action IN ?", actions)
+	}
+	if err := query.Delete(&models.ContainerPermission{}).Error; err != nil {
+		return fmt.Errorf("failed to revoke permissions: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gsm/config"
+	"gsm/models"
+	"net/http"
+	"slices"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+const DISCORD_API_URL = "https://discord.com/api/v10"
+
+var discordEndpoint = oauth2.Endpoint{
+	AuthURL:   "https://discord.com/oauth2/authorize",
+	TokenURL:  "https://discord.com/api/oauth2/token",
+	AuthStyle: oauth2.AuthStyleInParams,
+}
+
+type discordProvider struct {
+	oauth2Config oauth2.Config
+	guildID      string
+	roles        map[models.UserRole][]string // Discord role IDs mapped to each role
+}
+
+func newDiscordProvider(cfg *config.Config) (*discordProvider, error) {
+	err := requireSettings("discord", map[string]string{
+		"DISCORD_CLIENT_ID":     cfg.DiscordClientID,
+		"DISCORD_CLIENT_SECRET": cfg.DiscordSecret,
+		"DISCORD_REDIRECT_URL":  cfg.DiscordRedirect,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &discordProvider{
+		oauth2Config: oauth2.Config{
+			ClientID:     cfg.DiscordClientID,
+			ClientSecret: cfg.DiscordSecret,
+			RedirectURL:  cfg.DiscordRedirect,
+			Scopes:       []string{"identify", "email"},
+			Endpoint:     discordEndpoint,
+		},
+		guildID: cfg.DiscordGuildID,
+		roles: map[models.UserRole][]string{
+			models.UserRoleAdmin:   splitList(cfg.DiscordAdminRoles),
+			models.UserRoleMod:     splitList(cfg.DiscordModRoles),
+			models.UserRoleDefault: splitList(cfg.DiscordUserRoles),
+		},
+	}
+
+	// Reading the member's roles needs an extra scope
+	if provider.guildID != "" {
+		provider.oauth2Config.Scopes = append(provider.oauth2Config.Scopes, "guilds.members.read")
+	}
+
+	return provider, nil
+}
+
+func (p *discordProvider) Name() string        { return "discord" }
+func (p *discordProvider) DisplayName() string { return "Discord" }
+func (p *discordProvider) Type() ProviderType  { return ProviderTypeOAuth }
+
+func (p *discordProvider) AuthCodeURL(state string) (string, error) {
+	return p.oauth2Config.AuthCodeURL(state), nil
+}
+
+func (p *discordProvider) Exchange(ctx context.Context, code string) (*Identity, error) {
+	token, err := p.oauth2Config.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code for token: %v", err)
+	}
+
+	var user struct {
+		ID       string `json:"id"`
+		Email    string `json:"email"`
+		Verified bool   `json:"verified"`
+		Avatar   string `json:"avatar"`
+	}
+	client := p.oauth2Config.Client(ctx, token)
+	if err := fetchJSON(ctx, client, DISCORD_API_URL+"/users/@me", &user); err != nil {
+		return nil, err
+	}
+
+	if user.Email == "" || !user.Verified {
+		return nil, errors.New("no verified email")
+	}
+
+	identity := &Identity{Email: user.Email}
+	if user.Avatar != "" {
+		identity.Picture = fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png", user.ID, user.Avatar)
+	}
+
+	if p.guildID != "" {
+		role, err := p.guildRole(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		identity.Role = role
+	}
+
+	return identity, nil
+}
+
+// guildRole maps the member's Discord roles in the configured guild onto a role, the highest match wins
+func (p *discordProvider) guildRole(ctx context.Context, client *http.Client) (models.UserRole, error) {
+	var member struct {
+		Roles []string `json:"roles"`
+	}
+	err := fetchJSON(ctx, client, fmt.Sprintf("%s/users/@me/guilds/%s/member", DISCORD_API_URL, p.guildID), &member)
+	if errors.Is(err, errNotFound) {
+		return "", fmt.Errorf("%w: not a member of the guild", ErrNotAllowed)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for i := len(models.Roles) - 1; i >= 0; i-- {
+		role := models.Roles[i]
+		for _, id := range p.roles[role] {
+			if slices.Contains(member.Roles, id) {
+				return role, nil
+			}
+		}
+	}
+
+	// Without user roles configured every member may sign in
+	if len(p.roles[models.UserRoleDefault]) == 0 {
+		return models.UserRoleDefault, nil
+	}
+	return "", fmt.Errorf("%w: no allowed guild role", ErrNotAllowed)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gsm/config"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+const GITHUB_API_URL = "https://api.github.com"
+
+type githubProvider struct {
+	oauth2Config oauth2.Config
+}
+
+func newGitHubProvider(cfg *config.Config) (*githubProvider, error) {
+	err := requireSettings("github", map[string]string{
+		"GITHUB_CLIENT_ID":     cfg.GitHubClientID,
+		"GITHUB_CLIENT_SECRET": cfg.GitHubSecret,
+		"GITHUB_REDIRECT_URL":  cfg.GitHubRedirect,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &githubProvider{oauth2Config: oauth2.Config{
+		ClientID:     cfg.GitHubClientID,
+		ClientSecret: cfg.GitHubSecret,
+		RedirectURL:  cfg.GitHubRedirect,
+		Scopes:       []string{"read:user", "user:email"},
+		Endpoint:     github.Endpoint,
+	}}, nil
+}
+
+func (p *githubProvider) Name() string        { return "github" }
+func (p *githubProvider) DisplayName() string { return "GitHub" }
+func (p *githubProvider) Type() ProviderType  { return ProviderTypeOAuth }
+
+func (p *githubProvider) AuthCodeURL(state string) (string, error) {
+	return p.oauth2Config.AuthCodeURL(state), nil
+}
+
+// Exchange uses the primary verified email, the profile email is optional and may be unverified
+func (p *githubProvider) Exchange(ctx context.Context, code string) (*Identity, error) {
+	token, err := p.oauth2Config.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code for token: %v", err)
+	}
+	client := p.oauth2Config.Client(ctx, token)
+
+	var user struct {
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := fetchJSON(ctx, client, GITHUB_API_URL+"/user", &user); err != nil {
+		return nil, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := fetchJSON(ctx, client, GITHUB_API_URL+"/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	for _, email := range emails {
+		if email.Primary && email.Verified {
+			return &Identity{Email: email.Email, Picture: user.AvatarURL}, nil
+		}
+	}
+	return nil, errors.New("no verified primary email")
+}
+
+// fetchJSON requests url with the authorized client and decodes the JSON response into v
+func fetchJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to request %s: %s", url, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %v", url, err)
+	}
+	return nil
+}
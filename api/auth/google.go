@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gsm/config"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/idtoken"
+)
+
+type googleProvider struct {
+	oauth2Config oauth2.Config
+}
+
+func newGoogleProvider(cfg *config.Config) (*googleProvider, error) {
+	err := requireSettings("google", map[string]string{
+		"GOOGLE_CLIENT_ID":     cfg.GoogleClientID,
+		"GOOGLE_CLIENT_SECRET": cfg.GoogleSecret,
+		"GOOGLE_REDIRECT_URL":  cfg.GoogleRedirect,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &googleProvider{oauth2Config: oauth2.Config{
+		ClientID:     cfg.GoogleClientID,
+		ClientSecret: cfg.GoogleSecret,
+		RedirectURL:  cfg.GoogleRedirect, // Make sure this matches your redirect URI
+		Scopes: []string{
+			"https://www.googleapis.com/auth/userinfo.email",
+			"https://www.googleapis.com/auth/userinfo.profile",
+		},
+		Endpoint: google.Endpoint,
+	}}, nil
+}
+
+func (p *googleProvider) Name() string        { return "google" }
+func (p *googleProvider) DisplayName() string { return "Google" }
+func (p *googleProvider) Type() ProviderType  { return ProviderTypeOAuth }
+
+func (p *googleProvider) AuthCodeURL(state string) (string, error) {
+	return p.oauth2Config.AuthCodeURL(state, oauth2.AccessTypeOffline), nil
+}
+
+func (p *googleProvider) Exchange(ctx context.Context, code string) (*Identity, error) {
+	token, err := p.oauth2Config.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code for token: %v", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("id token missing")
+	}
+
+	// Validate the ID token
+	payload, err := idtoken.Validate(ctx, rawIDToken, p.oauth2Config.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %v", err)
+	}
+
+	email, ok := payload.Claims["email"].(string)
+	if !ok {
+		return nil, errors.New("email not found in token")
+	}
+	picture, _ := payload.Claims["picture"].(string)
+
+	return &Identity{Email: email, Picture: picture}, nil
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"gsm/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const MIN_PASSWORD_LENGTH = 8
+
+// Compared against when the user does not exist, so both cases take as long
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("gsm-dummy-password"), bcrypt.DefaultCost)
+
+// localProvider checks passwords stored as bcrypt hashes on the allowed users
+type localProvider struct {
+	db *gorm.DB
+}
+
+func newLocalProvider(db *gorm.DB) *localProvider {
+	return &localProvider{db: db}
+}
+
+func (p *localProvider) Name() string        { return "local" }
+func (p *localProvider) DisplayName() string { return "Email and password" }
+func (p *localProvider) Type() ProviderType  { return ProviderTypePassword }
+
+func (p *localProvider) Authenticate(ctx context.Context, email, password string) (*Identity, error) {
+	var user models.AllowedUser
+	if err := p.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil || user.PasswordHash == "" {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Identity{Email: user.Email}, nil
+}
+
+// HashPassword validates the password and returns its bcrypt hash
+func HashPassword(password string) (string, error) {
+	if len(password) < MIN_PASSWORD_LENGTH {
+		return "", fmt.Errorf("password must be at least %d characters", MIN_PASSWORD_LENGTH)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %v", err)
+	}
+	return string(hash), nil
+}
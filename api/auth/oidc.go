@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gsm/config"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+const OIDC_DISCOVERY_TIMEOUT = 10 * time.Second
+
+// oidcProvider signs users in with any OpenID Connect issuer, e.g. Authentik or Keycloak.
+// Discovery happens on first use so the API starts even while the issuer is unreachable.
+type oidcProvider struct {
+	issuer       string
+	displayName  string
+	oauth2Config oauth2.Config
+
+	mu       sync.Mutex
+	verifier *oidc.IDTokenVerifier
+}
+
+func newOIDCProvider(cfg *config.Config) (*oidcProvider, error) {
+	err := requireSettings("oidc", map[string]string{
+		"OIDC_ISSUER":        cfg.OIDCIssuer,
+		"OIDC_CLIENT_ID":     cfg.OIDCClientID,
+		"OIDC_CLIENT_SECRET": cfg.OIDCSecret,
+		"OIDC_REDIRECT_URL":  cfg.OIDCRedirect,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &oidcProvider{
+		issuer:      cfg.OIDCIssuer,
+		displayName: cfg.OIDCName,
+		oauth2Config: oauth2.Config{
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCSecret,
+			RedirectURL:  cfg.OIDCRedirect,
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+	}, nil
+}
+
+func (p *oidcProvider) Name() string        { return "oidc" }
+func (p *oidcProvider) DisplayName() string { return p.displayName }
+func (p *oidcProvider) Type() ProviderType  { return ProviderTypeOAuth }
+
+// discover fetches the issuer's endpoints and keys once it succeeds
+func (p *oidcProvider) discover(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.verifier != nil {
+		return p.verifier, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, OIDC_DISCOVERY_TIMEOUT)
+	defer cancel()
+
+	provider, err := oidc.NewProvider(ctx, p.issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover %s: %v", p.issuer, err)
+	}
+
+	p.oauth2Config.Endpoint = provider.Endpoint()
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.oauth2Config.ClientID})
+	return p.verifier, nil
+}
+
+func (p *oidcProvider) AuthCodeURL(state string) (string, error) {
+	if _, err := p.discover(context.Background()); err != nil {
+		return "", err
+	}
+	return p.oauth2Config.AuthCodeURL(state), nil
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code string) (*Identity, error) {
+	verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.oauth2Config.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code for token: %v", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("id token missing")
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %v", err)
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified *bool  `json:"email_verified"`
+		Picture       string `json:"picture"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse id token claims: %v", err)
+	}
+	if claims.Email == "" {
+		return nil, errors.New("email not found in token")
+	}
+	// Issuers that omit the claim are trusted, an explicit false is not
+	if claims.EmailVerified != nil && !*claims.EmailVerified {
+		return nil, errors.New("email is not verified")
+	}
+
+	return &Identity{Email: claims.Email, Picture: claims.Picture}, nil
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gsm/config"
+	"gsm/models"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrNotAllowed         = errors.New("not allowed to sign in")
+	errNotFound           = errors.New("not found")
+)
+
+type ProviderType string
+
+const (
+	ProviderTypeOAuth    ProviderType = "oauth"    // Redirects to the identity provider
+	ProviderTypePassword ProviderType = "password" // Credentials are posted to the API
+)
+
+// Identity is the signed in user as reported by a provider
+type Identity struct {
+	Email   string
+	Picture string
+	Role    models.UserRole // Set by providers that decide the role themselves, empty to use the allowed user's role
+}
+
+type Provider interface {
+	Name() string        // Used in routes, e.g. "github"
+	DisplayName() string // Shown on the sign in page
+	Type() ProviderType
+}
+
+// OAuthProvider signs users in with an authorization code flow
+type OAuthProvider interface {
+	Provider
+	AuthCodeURL(state string) (string, error)
+	Exchange(ctx context.Context, code string) (*Identity, error)
+}
+
+// PasswordProvider signs users in with an email and password
+type PasswordProvider interface {
+	Provider
+	Authenticate(ctx context.Context, email, password string) (*Identity, error)
+}
+
+// Load creates the providers listed in AUTH_PROVIDERS, in order
+func Load(db *gorm.DB) ([]Provider, error) {
+	cfg := config.Get()
+
+	var providers []Provider
+	for _, name := range strings.Split(cfg.AuthProviders, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		var provider Provider
+		var err error
+		switch name {
+		case "google":
+			provider, err = newGoogleProvider(cfg)
+		case "oidc":
+			provider, err = newOIDCProvider(cfg)
+		case "github":
+			provider, err = newGitHubProvider(cfg)
+		case "discord":
+			provider, err = newDiscordProvider(cfg)
+		case "local":
+			provider = newLocalProvider(db)
+		default:
+			err = fmt.Errorf("unknown auth provider %q", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	if len(providers) == 0 {
+		return nil, errors.New("no auth providers configured")
+	}
+	return providers, nil
+}
+
+// requireSettings returns an error naming every empty environment variable, sorted so it reads the same on each run
+func requireSettings(provider string, settings map[string]string) error {
+	var missing []string
+	for key, value := range settings {
+		if value == "" {
+			missing = append(missing, key)
+		}
+	}
+
+	switch len(missing) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("%s is required for the %s auth provider", missing[0], provider)
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("%s are required for the %s auth provider", strings.Join(missing, ", "), provider)
+}
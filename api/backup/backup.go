@@ -0,0 +1,402 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SNAPSHOT_EXT          = ".tar.gz"
+	SNAPSHOT_TIME_LAYOUT  = "20060102T150405.000000000Z" // Nanoseconds keep snapshots taken within the same second apart
+	SNAPSHOT_PARSE_LAYOUT = "20060102T150405Z"           // Accepts names with and without fractional seconds
+)
+
+type Client interface {
+	CreateSnapshot(container string, retention int) (*Snapshot, error)
+	ListSnapshots(container string) ([]Snapshot, error)
+	Snapshot(container string, name string) (*Snapshot, error)
+	RestoreSnapshot(container string, name string) error
+	DeleteSnapshot(container string, name string) error
+}
+
+type backupClient struct {
+	volumesDir string
+	backupsDir string
+	locks      sync.Map // container name -> *sync.Mutex
+}
+
+func NewClient(volumesDir, backupsDir string) Client {
+	return &backupClient{volumesDir: volumesDir, backupsDir: backupsDir}
+}
+
+// CreateSnapshot archives the container's volume directory and prunes snapshots beyond retention
+func (b *backupClient) CreateSnapshot(container string, retention int) (*Snapshot, error) {
+	volumeDir, snapshotDir, err := b.containerDirs(container)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := b.lock(container)
+	defer unlock()
+
+	if _, err := os.Stat(volumeDir); err != nil {
+		return nil, fmt.Errorf("failed to find volume directory: %v", err)
+	}
+
+	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %v", err)
+	}
+
+	createdAt := time.Now().UTC()
+	name := createdAt.Format(SNAPSHOT_TIME_LAYOUT) + SNAPSHOT_EXT
+	snapshotPath := filepath.Join(snapshotDir, name)
+
+	// Write to a temporary file first so a failed backup never shows up as a snapshot
+	tmp, err := os.CreateTemp(snapshotDir, ".snapshot-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeArchive(tmp, volumeDir); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to archive volume: %v", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot: %v", err)
+	}
+
+	// Rename replaces silently, an existing snapshot must never be lost to a new one
+	if _, err := os.Lstat(snapshotPath); err == nil {
+		return nil, fmt.Errorf("snapshot %s already exists", name)
+	}
+
+	if err := os.Rename(tmp.Name(), snapshotPath); err != nil {
+		return nil, fmt.Errorf("failed to save snapshot: %v", err)
+	}
+
+	info, err := os.Stat(snapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot info: %v", err)
+	}
+
+	if err := b.prune(container, snapshotDir, retention); err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Name:      name,
+		Container: container,
+		Size:      info.Size(),
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// ListSnapshots returns the container's snapshots, newest first
+func (b *backupClient) ListSnapshots(container string) ([]Snapshot, error) {
+	_, snapshotDir, err := b.containerDirs(container)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(snapshotDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Snapshot{}, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %v", err)
+	}
+
+	snapshots := []Snapshot{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), SNAPSHOT_EXT) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		snapshots = append(snapshots, newSnapshot(container, info))
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+
+	return snapshots, nil
+}
+
+// Snapshot returns one of the container's snapshots, or an error when it does not exist
+func (b *backupClient) Snapshot(container string, name string) (*Snapshot, error) {
+	_, snapshotDir, err := b.containerDirs(container)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotPath, err := snapshotFile(snapshotDir, name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(snapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot not found: %s", name)
+	}
+
+	snapshot := newSnapshot(container, info)
+	return &snapshot, nil
+}
+
+// RestoreSnapshot replaces the container's volume directory with the contents of the snapshot
+func (b *backupClient) RestoreSnapshot(container string, name string) error {
+	volumeDir, snapshotDir, err := b.containerDirs(container)
+	if err != nil {
+		return err
+	}
+
+	snapshotPath, err := snapshotFile(snapshotDir, name)
+	if err != nil {
+		return err
+	}
+
+	unlock := b.lock(container)
+	defer unlock()
+
+	file, err := os.Open(snapshotPath)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %v", err)
+	}
+	defer file.Close()
+
+	// Extract next to the volume directory so the final swap is a rename on the same filesystem
+	stagingDir, err := os.MkdirTemp(b.volumesDir, "."+container+".restore-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %v", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	if err := os.Chmod(stagingDir, 0755); err != nil {
+		return fmt.Errorf("failed to prepare staging directory: %v", err)
+	}
+
+	if err := extractArchive(file, stagingDir); err != nil {
+		return fmt.Errorf("failed to extract snapshot: %v", err)
+	}
+
+	oldDir := stagingDir + ".old"
+	hadVolume := true
+	if err := os.Rename(volumeDir, oldDir); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to move current volume aside: %v", err)
+		}
+		hadVolume = false
+	}
+
+	if err := os.Rename(stagingDir, volumeDir); err != nil {
+		if hadVolume {
+			os.Rename(oldDir, volumeDir)
+		}
+		return fmt.Errorf("failed to replace volume: %v", err)
+	}
+
+	if hadVolume {
+		os.RemoveAll(oldDir)
+	}
+
+	return nil
+}
+
+func (b *backupClient) DeleteSnapshot(container string, name string) error {
+	_, snapshotDir, err := b.containerDirs(container)
+	if err != nil {
+		return err
+	}
+
+	snapshotPath, err := snapshotFile(snapshotDir, name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(snapshotPath); err != nil {
+		return fmt.Errorf("failed to delete snapshot: %v", err)
+	}
+
+	return nil
+}
+
+func (b *backupClient) prune(container, snapshotDir string, retention int) error {
+	if retention <= 0 {
+		return nil
+	}
+
+	snapshots, err := b.ListSnapshots(container)
+	if err != nil {
+		return err
+	}
+
+	for i := retention; i < len(snapshots); i++ {
+		if err := os.Remove(filepath.Join(snapshotDir, snapshots[i].Name)); err != nil {
+			return fmt.Errorf("failed to remove old snapshot %s: %v", snapshots[i].Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (b *backupClient) lock(container string) func() {
+	mu, _ := b.locks.LoadOrStore(container, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+func (b *backupClient) containerDirs(container string) (string, string, error) {
+	if container == "" || container != filepath.Base(container) || strings.HasPrefix(container, ".") {
+		return "", "", fmt.Errorf("invalid container name: %q", container)
+	}
+	return filepath.Join(b.volumesDir, container), filepath.Join(b.backupsDir, container), nil
+}
+
+func newSnapshot(container string, info os.FileInfo) Snapshot {
+	createdAt, err := time.Parse(SNAPSHOT_PARSE_LAYOUT, strings.TrimSuffix(info.Name(), SNAPSHOT_EXT))
+	if err != nil {
+		createdAt = info.ModTime()
+	}
+
+	return Snapshot{
+		Name:      info.Name(),
+		Container: container,
+		Size:      info.Size(),
+		CreatedAt: createdAt,
+	}
+}
+
+func snapshotFile(snapshotDir, name string) (string, error) {
+	if name != filepath.Base(name) || !strings.HasSuffix(name, SNAPSHOT_EXT) {
+		return "", fmt.Errorf("invalid snapshot name: %q", name)
+	}
+
+	snapshotPath := filepath.Join(snapshotDir, name)
+	if _, err := os.Stat(snapshotPath); err != nil {
+		return "", fmt.Errorf("snapshot not found: %s", name)
+	}
+
+	return snapshotPath, nil
+}
+
+func writeArchive(writer io.Writer, dir string) error {
+	gzipWriter := gzip.NewWriter(writer)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Only regular files and directories are archived
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relPath)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(tarWriter, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+func extractArchive(reader io.Reader, destination string) error {
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Sanitize file path to prevent tar slip
+		filePath := filepath.Join(destination, header.Name)
+		if !strings.HasPrefix(filePath, filepath.Clean(destination)+string(os.PathSeparator)) {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(filePath, os.FileMode(header.Mode).Perm()|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+				return err
+			}
+
+			dstFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return err
+			}
+
+			_, err = io.Copy(dstFile, tarReader)
+			dstFile.Close()
+			if err != nil {
+				return err
+			}
+
+			os.Chtimes(filePath, header.ModTime, header.ModTime)
+		}
+	}
+}
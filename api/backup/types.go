@@ -0,0 +1,10 @@
+package backup
+
+import "time"
+
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Container string    `json:"container"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
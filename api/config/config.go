@@ -3,24 +3,60 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 )
 
+const DEFAULT_JWT_SECRET = "secret"
+
 type Config struct {
-	AppEnv         string
-	Port           string
-	HostHome       string
-	DataDir        string
-	VolumeDir      string
-	DBFilename     string
-	AllowOrigin    string
-	CookieDomain   string
-	SSLCertFile    string
-	SSLKeyFile     string
-	GoogleClientID string
-	GoogleSecret   string
-	GoogleRedirect string
-	JWTSecret      string
-	AdminEmail     string
+	AppEnv              string
+	Port                string
+	HostHome            string
+	DataDir             string
+	VolumeDir           string
+	DBFilename          string
+	AllowOrigin         string
+	CookieDomain        string
+	SSLCertFile         string
+	SSLKeyFile          string
+	AuthProviders       string
+	GoogleClientID      string
+	GoogleSecret        string
+	GoogleRedirect      string
+	OIDCName            string
+	OIDCIssuer          string
+	OIDCClientID        string
+	OIDCSecret          string
+	OIDCRedirect        string
+	GitHubClientID      string
+	GitHubSecret        string
+	GitHubRedirect      string
+	DiscordClientID     string
+	DiscordSecret       string
+	DiscordRedirect     string
+	DiscordGuildID      string
+	DiscordAdminRoles   string
+	DiscordModRoles     string
+	DiscordUserRoles    string
+	JWTSecret           string
+	EncryptionKey       string
+	AdminEmail          string
+	AdminPassword       string
+	BackupDir           string
+	BackupRetain        int
+	UploadDir           string
+	UploadMaxSize       int64
+	ExtractMaxSize      int64
+	ExtractMaxEntries   int
+	SearchMaxResults    int
+	SearchMaxFileSize   int64
+	SearchTimeout       time.Duration
+	GameHost            string
+	RegistryURL         string
+	UpdateCheckInterval time.Duration
+	AccessTokenTTL      time.Duration
+	SessionTTL          time.Duration
 }
 
 var cfg *Config
@@ -28,21 +64,59 @@ var cfg *Config
 func Get() *Config {
 	if cfg == nil {
 		cfg = &Config{
-			AppEnv:         getEnvOrDefault("APP_ENV", "development"),
-			Port:           getEnvOrDefault("PORT", "8181"),
-			HostHome:       requiredEnv("HOST_HOME"),
-			DataDir:        getEnvOrDefault("DATA_DIR", "/gsm-data"),
-			VolumeDir:      getEnvOrDefault("VOLUME_DIR", "/volumes"),
-			DBFilename:     getEnvOrDefault("DB_FILENAME", "app.db"),
-			AllowOrigin:    getEnvOrDefault("ALLOW_ORIGIN", "http://localhost:8282"),
-			CookieDomain:   getEnvOrDefault("COOKIE_DOMAIN", "localhost"),
-			SSLCertFile:    os.Getenv("SSL_CERT_FILE"),
-			SSLKeyFile:     os.Getenv("SSL_KEY_FILE"),
-			GoogleClientID: requiredEnv("GOOGLE_CLIENT_ID"),
-			GoogleSecret:   requiredEnv("GOOGLE_CLIENT_SECRET"),
-			GoogleRedirect: requiredEnv("GOOGLE_REDIRECT_URL"),
-			JWTSecret:      getEnvOrDefault("JWT_SECRET", "secret"),
-			AdminEmail:     requiredEnv("ADMIN_EMAIL"),
+			AppEnv:              getEnvOrDefault("APP_ENV", "development"),
+			Port:                getEnvOrDefault("PORT", "8181"),
+			HostHome:            requiredEnv("HOST_HOME"),
+			DataDir:             getEnvOrDefault("DATA_DIR", "/gsm-data"),
+			VolumeDir:           getEnvOrDefault("VOLUME_DIR", "/volumes"),
+			DBFilename:          getEnvOrDefault("DB_FILENAME", "app.db"),
+			AllowOrigin:         getEnvOrDefault("ALLOW_ORIGIN", "http://localhost:8282"),
+			CookieDomain:        getEnvOrDefault("COOKIE_DOMAIN", "localhost"),
+			SSLCertFile:         os.Getenv("SSL_CERT_FILE"),
+			SSLKeyFile:          os.Getenv("SSL_KEY_FILE"),
+			AuthProviders:       getEnvOrDefault("AUTH_PROVIDERS", "google"),
+			GoogleClientID:      os.Getenv("GOOGLE_CLIENT_ID"),
+			GoogleSecret:        os.Getenv("GOOGLE_CLIENT_SECRET"),
+			GoogleRedirect:      os.Getenv("GOOGLE_REDIRECT_URL"),
+			OIDCName:            getEnvOrDefault("OIDC_NAME", "Single sign-on"),
+			OIDCIssuer:          os.Getenv("OIDC_ISSUER"),
+			OIDCClientID:        os.Getenv("OIDC_CLIENT_ID"),
+			OIDCSecret:          os.Getenv("OIDC_CLIENT_SECRET"),
+			OIDCRedirect:        os.Getenv("OIDC_REDIRECT_URL"),
+			GitHubClientID:      os.Getenv("GITHUB_CLIENT_ID"),
+			GitHubSecret:        os.Getenv("GITHUB_CLIENT_SECRET"),
+			GitHubRedirect:      os.Getenv("GITHUB_REDIRECT_URL"),
+			DiscordClientID:     os.Getenv("DISCORD_CLIENT_ID"),
+			DiscordSecret:       os.Getenv("DISCORD_CLIENT_SECRET"),
+			DiscordRedirect:     os.Getenv("DISCORD_REDIRECT_URL"),
+			DiscordGuildID:      os.Getenv("DISCORD_GUILD_ID"),
+			DiscordAdminRoles:   os.Getenv("DISCORD_ADMIN_ROLES"),
+			DiscordModRoles:     os.Getenv("DISCORD_MOD_ROLES"),
+			DiscordUserRoles:    os.Getenv("DISCORD_USER_ROLES"),
+			JWTSecret:           getEnvOrDefault("JWT_SECRET", DEFAULT_JWT_SECRET),
+			EncryptionKey:       os.Getenv("ENCRYPTION_KEY"),
+			AdminEmail:          requiredEnv("ADMIN_EMAIL"),
+			AdminPassword:       os.Getenv("ADMIN_PASSWORD"),
+			BackupDir:           getEnvOrDefault("BACKUP_DIR", "/backups"),
+			BackupRetain:        getIntEnvOrDefault("BACKUP_RETAIN", 5),
+			UploadDir:           getEnvOrDefault("UPLOAD_DIR", "/uploads"),
+			UploadMaxSize:       int64(getIntEnvOrDefault("UPLOAD_MAX_SIZE", 20<<30)),
+			ExtractMaxSize:      int64(getIntEnvOrDefault("EXTRACT_MAX_SIZE", 20<<30)),
+			ExtractMaxEntries:   getIntEnvOrDefault("EXTRACT_MAX_ENTRIES", 100000),
+			SearchMaxResults:    getIntEnvOrDefault("SEARCH_MAX_RESULTS", 1000),
+			SearchMaxFileSize:   int64(getIntEnvOrDefault("SEARCH_MAX_FILE_SIZE", 10<<20)),
+			SearchTimeout:       getDurationEnvOrDefault("SEARCH_TIMEOUT", 30*time.Second),
+			GameHost:            getEnvOrDefault("GAME_HOST", "localhost"),
+			RegistryURL:         getEnvOrDefault("REGISTRY_URL", "https://registry-1.docker.io"),
+			UpdateCheckInterval: getDurationEnvOrDefault("UPDATE_CHECK_INTERVAL", 6*time.Hour),
+			AccessTokenTTL:      getDurationEnvOrDefault("ACCESS_TOKEN_TTL", 15*time.Minute),
+			SessionTTL:          getDurationEnvOrDefault("SESSION_TTL", 72*time.Hour),
+		}
+
+		// Secrets stored in the database fall back to the JWT secret as their key, never to the publicly known default.
+		// Without a key secrets cannot be stored, see secrets.ErrNoKey.
+		if cfg.EncryptionKey == "" && cfg.JWTSecret != DEFAULT_JWT_SECRET {
+			cfg.EncryptionKey = cfg.JWTSecret
 		}
 	}
 
@@ -63,3 +137,29 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getIntEnvOrDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Environment variable %s must be an integer", key)
+	}
+	return intValue
+}
+
+func getDurationEnvOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Environment variable %s must be a duration, e.g. 15m", key)
+	}
+	return duration
+}
@@ -3,25 +3,41 @@ package docker
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
 )
 
 const DOCKER_TIME_LAYOUT = time.RFC3339Nano
 
+const (
+	UPDATE_ASIDE_SUFFIX     = "-previous-"     // Name suffix of the old container while it is being replaced
+	UPDATE_HEALTH_GRACE     = 10 * time.Second // How long a new container without a healthcheck has to stay running
+	UPDATE_HEALTH_TIMEOUT   = 2 * time.Minute  // How long a new container with a healthcheck may take to become healthy
+	UPDATE_HEALTH_POLL      = time.Second
+	UPDATE_ROLLBACK_TIMEOUT = time.Minute
+)
+
 type Client interface {
 	ListContainers(ctx context.Context) ([]ContainerListItem, error)
 	InspectContainer(ctx context.Context, id string) (*ContainerInspect, error)
+	ContainerName(ctx context.Context, id string) (string, error)
 	CreateContainer(ctx context.Context, createConfig *ContainerCreate) (string, []string, error)
 	RemoveContainer(ctx context.Context, id string) error
 	StartContainer(ctx context.Context, id string) error
@@ -30,12 +46,25 @@ type Client interface {
 	ContainerLogs(ctx context.Context, id string, follow bool, tail int) (io.ReadCloser, error)
 	ContainerExec(ctx context.Context, id string, cmd string) (string, error)
 	ListImages(ctx context.Context) ([]image.Summary, error)
-	PullImage(ctx context.Context, imageName string) (io.ReadCloser, error)
+	ImageDigests(ctx context.Context, id string) ([]string, error)
+	PullImage(ctx context.Context, imageName string, registryAuth string) (io.ReadCloser, error)
 	RemoveImage(ctx context.Context, id string) error
+	SearchImages(ctx context.Context, term string, limit int) ([]registry.SearchResult, error)
+	RegistryLogin(ctx context.Context, host, username, password string) error
 	ContainerConnections(ctx context.Context) (map[string]int, error)
 	ContainerConnectionsByID(ctx context.Context, containerID string) (map[string]int, error)
 	StreamEvents(ctx context.Context) (<-chan events.Message, <-chan error)
 	UpdateContainer(ctx context.Context, id string, createConfig *ContainerCreate) (string, []string, error)
+	RecoverUpdates(ctx context.Context) ([]string, error)
+	ContainerCreateConfig(ctx context.Context, id string) (*ContainerCreate, error)
+	AttachContainer(ctx context.Context, id string) (types.HijackedResponse, error)
+	ResizeContainerTTY(ctx context.Context, id string, height uint, width uint) error
+	CreateExecSession(ctx context.Context, id string, cmd []string, tty bool) (string, error)
+	AttachExecSession(ctx context.Context, execID string, tty bool) (types.HijackedResponse, error)
+	ResizeExecTTY(ctx context.Context, execID string, height uint, width uint) error
+	InspectExecSession(ctx context.Context, execID string) (*ExecInspect, error)
+	ContainerStats(ctx context.Context, id string) (*ContainerStats, error)
+	StreamContainerStats(ctx context.Context, id string) (<-chan ContainerStats, <-chan error)
 }
 
 type dockerClient struct {
@@ -60,11 +89,12 @@ func (d *dockerClient) ListContainers(ctx context.Context) ([]ContainerListItem,
 	containers := make([]ContainerListItem, len(list))
 	for i, container := range list {
 		containers[i] = ContainerListItem{
-			ID:     container.ID,
-			Names:  container.Names,
-			Image:  container.Image,
-			State:  container.State,
-			Status: container.Status,
+			ID:      container.ID,
+			Names:   container.Names,
+			Image:   container.Image,
+			ImageID: container.ImageID,
+			State:   container.State,
+			Status:  container.Status,
 		}
 	}
 
@@ -122,6 +152,23 @@ func (d *dockerClient) InspectContainer(ctx context.Context, id string) (*Contai
 		}
 	}
 
+	var health *ContainerHealth
+	if inspect.State.Health != nil {
+		health = &ContainerHealth{
+			Status:        inspect.State.Health.Status,
+			FailingStreak: inspect.State.Health.FailingStreak,
+			Log:           make([]HealthProbe, len(inspect.State.Health.Log)),
+		}
+		for i, probe := range inspect.State.Health.Log {
+			health.Log[i] = HealthProbe{
+				Start:    probe.Start,
+				End:      probe.End,
+				ExitCode: probe.ExitCode,
+				Output:   probe.Output,
+			}
+		}
+	}
+
 	return &ContainerInspect{
 		ID:      inspect.ID,
 		Created: created,
@@ -130,6 +177,7 @@ func (d *dockerClient) InspectContainer(ctx context.Context, id string) (*Contai
 			Running:    inspect.State.Running,
 			StartedAt:  startedAt,
 			FinishedAt: finishedAt,
+			Health:     health,
 		},
 		Name:   inspect.Name,
 		Mounts: mounts,
@@ -137,9 +185,11 @@ func (d *dockerClient) InspectContainer(ctx context.Context, id string) (*Contai
 			Image:        inspect.Config.Image,
 			Env:          inspect.Config.Env,
 			Tty:          inspect.Config.Tty,
+			OpenStdin:    inspect.Config.OpenStdin,
 			AttachStdin:  inspect.Config.AttachStdin,
 			AttachStdout: inspect.Config.AttachStdout,
 			AttachStderr: inspect.Config.AttachStderr,
+			Healthcheck:  healthcheckFromDocker(inspect.Config.Healthcheck),
 		},
 		HostConfig: ContainerHostConfig{
 			PortBindings:  portBindings,
@@ -152,6 +202,15 @@ func (d *dockerClient) InspectContainer(ctx context.Context, id string) (*Contai
 	}, nil
 }
 
+// ContainerName resolves a container ID or name to its name without the leading slash
+func (d *dockerClient) ContainerName(ctx context.Context, id string) (string, error) {
+	inspect, err := d.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container: %v", err)
+	}
+	return strings.TrimPrefix(inspect.Name, "/"), nil
+}
+
 func (d *dockerClient) CreateContainer(ctx context.Context, createConfig *ContainerCreate) (string, []string, error) {
 	config, hostConfig, err := createConfig.ToDockerConfig(d.volumeBaseDir)
 	if err != nil {
@@ -240,8 +299,41 @@ func (d *dockerClient) ListImages(ctx context.Context) ([]image.Summary, error)
 	return d.cli.ImageList(ctx, image.ListOptions{})
 }
 
-func (d *dockerClient) PullImage(ctx context.Context, imageName string) (io.ReadCloser, error) {
-	return d.cli.ImagePull(ctx, imageName, image.PullOptions{})
+// ImageDigests returns the repository digests of a local image, e.g. "nginx@sha256:...".
+// Images that were never pushed to or pulled from a registry have none.
+func (d *dockerClient) ImageDigests(ctx context.Context, id string) ([]string, error) {
+	inspect, _, err := d.cli.ImageInspectWithRaw(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image %s: %v", id, err)
+	}
+	return inspect.RepoDigests, nil
+}
+
+// PullImage pulls the image, registryAuth is an encoded registry.AuthConfig or empty for anonymous pulls
+func (d *dockerClient) PullImage(ctx context.Context, imageName string, registryAuth string) (io.ReadCloser, error) {
+	return d.cli.ImagePull(ctx, imageName, image.PullOptions{RegistryAuth: registryAuth})
+}
+
+// SearchImages searches Docker Hub through the engine, so the daemon's proxy and mirror settings apply
+func (d *dockerClient) SearchImages(ctx context.Context, term string, limit int) ([]registry.SearchResult, error) {
+	results, err := d.cli.ImageSearch(ctx, term, registry.SearchOptions{Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search images: %v", err)
+	}
+	return results, nil
+}
+
+// RegistryLogin checks the credentials against the registry through the engine
+func (d *dockerClient) RegistryLogin(ctx context.Context, host, username, password string) error {
+	_, err := d.cli.RegistryLogin(ctx, registry.AuthConfig{
+		Username:      username,
+		Password:      password,
+		ServerAddress: host,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to log in to %s: %v", host, err)
+	}
+	return nil
 }
 
 func (d *dockerClient) RemoveImage(ctx context.Context, id string) error {
@@ -305,31 +397,442 @@ func (d *dockerClient) StreamEvents(ctx context.Context) (<-chan events.Message,
 	return d.cli.Events(ctx, events.ListOptions{})
 }
 
+// UpdateContainer replaces a container with one created from req.
+// The old container is renamed aside and stopped, and is only removed once the new one has been created and,
+// when the old one was running, started and found healthy. On any failure the new container is removed and
+// the old one is renamed back and restarted.
 func (d *dockerClient) UpdateContainer(ctx context.Context, id string, req *ContainerCreate) (string, []string, error) {
-	// Get current container details
 	inspect, err := d.cli.ContainerInspect(ctx, id)
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to inspect container %s: %v", id, err)
 	}
 
-	// Remove the container
-	if err := d.RemoveContainer(ctx, id); err != nil {
-		return "", nil, fmt.Errorf("failed to remove container %s: %v", id, err)
+	oldID := inspect.ID
+	oldName := strings.TrimPrefix(inspect.Name, "/")
+	wasRunning := inspect.State.Running
+
+	// Free the name for the new container
+	asideName := fmt.Sprintf("%s%s%d", oldName, UPDATE_ASIDE_SUFFIX, time.Now().Unix())
+	if err := d.cli.ContainerRename(ctx, oldID, asideName); err != nil {
+		return "", nil, fmt.Errorf("failed to rename container %s: %v", oldName, err)
+	}
+
+	update := &containerUpdate{d: d, oldID: oldID, oldName: oldName, asideName: asideName, wasRunning: wasRunning}
+
+	// The new container usually binds the same ports
+	if wasRunning {
+		if err := d.StopContainer(ctx, oldID); err != nil {
+			return "", nil, update.rollback(err)
+		}
+	}
+
+	newID, warnings, err := d.CreateContainer(ctx, req)
+	if err != nil {
+		return "", nil, update.rollback(err)
+	}
+	update.newID = newID
+
+	if wasRunning {
+		if err := d.StartContainer(ctx, newID); err != nil {
+			return "", nil, update.rollback(err)
+		}
+		if err := d.waitHealthy(ctx, newID); err != nil {
+			return "", nil, update.rollback(err)
+		}
+	}
+
+	if err := d.cli.ContainerRemove(ctx, oldID, container.RemoveOptions{Force: true}); err != nil {
+		warnings = append(warnings, fmt.Sprintf("failed to remove previous container %s: %v", asideName, err))
+	}
+
+	return newID, warnings, nil
+}
+
+// RecoverUpdates cleans up after updates that were interrupted, e.g. by a crash, and left the previous container
+// renamed aside. The previous container is removed when its replacement exists and renamed back otherwise,
+// the most recent one when there are several. It returns a description of every action taken.
+func (d *dockerClient) RecoverUpdates(ctx context.Context) ([]string, error) {
+	list, err := d.cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %v", err)
+	}
+
+	type aside struct {
+		id, name, original string
+		since              int64
+	}
+	names := make(map[string]bool)
+	var asides []aside
+	for _, c := range list {
+		for _, name := range c.Names {
+			name = strings.TrimPrefix(name, "/")
+			names[name] = true
+
+			i := strings.LastIndex(name, UPDATE_ASIDE_SUFFIX)
+			if i <= 0 {
+				continue
+			}
+			since, err := strconv.ParseInt(name[i+len(UPDATE_ASIDE_SUFFIX):], 10, 64)
+			if err != nil {
+				continue
+			}
+			asides = append(asides, aside{id: c.ID, name: name, original: name[:i], since: since})
+		}
+	}
+	sort.Slice(asides, func(i, j int) bool { return asides[i].since > asides[j].since })
+
+	var actions []string
+	var errs []error
+	for _, a := range asides {
+		if names[a.original] {
+			if err := d.cli.ContainerRemove(ctx, a.id, container.RemoveOptions{Force: true}); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove previous container %s: %v", a.name, err))
+				continue
+			}
+			actions = append(actions, fmt.Sprintf("removed previous container %s, %s exists", a.name, a.original))
+			continue
+		}
+
+		if err := d.cli.ContainerRename(ctx, a.id, a.original); err != nil {
+			errs = append(errs, fmt.Errorf("failed to rename previous container %s back to %s: %v", a.name, a.original, err))
+			continue
+		}
+		names[a.original] = true
+		actions = append(actions, fmt.Sprintf("restored previous container %s as %s", a.name, a.original))
+	}
+	return actions, errors.Join(errs...)
+}
+
+// containerUpdate tracks an UpdateContainer in progress so it can be rolled back
+type containerUpdate struct {
+	d          *dockerClient
+	oldID      string
+	oldName    string
+	asideName  string // Name of the old container while it is being replaced
+	wasRunning bool
+	newID      string // Empty until the new container has been created
+}
+
+// rollback restores the old container and returns the update error, along with any rollback failure
+func (u *containerUpdate) rollback(cause error) error {
+	// The rollback has to run even if the update was cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), UPDATE_ROLLBACK_TIMEOUT)
+	defer cancel()
+
+	var failures []string
+	if u.newID != "" {
+		if err := u.d.cli.ContainerRemove(ctx, u.newID, container.RemoveOptions{Force: true}); err != nil {
+			failures = append(failures, fmt.Sprintf("failed to remove new container: %v", err))
+		}
+	}
+	if err := u.d.cli.ContainerRename(ctx, u.oldID, u.oldName); err != nil {
+		failures = append(failures, fmt.Sprintf("failed to rename previous container back: %v", err))
+	}
+	if u.wasRunning {
+		if err := u.d.StartContainer(ctx, u.oldID); err != nil {
+			failures = append(failures, fmt.Sprintf("failed to restart previous container: %v", err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to update container %s: %v, rollback failed: %s, the previous container may remain as %s",
+			u.oldName, cause, strings.Join(failures, ", "), u.asideName)
+	}
+	return fmt.Errorf("failed to update container %s: %v, previous container restored", u.oldName, cause)
+}
+
+// waitHealthy waits for a started container to prove itself. Containers with a healthcheck have to report healthy
+// within UPDATE_HEALTH_TIMEOUT, others have to stay running for UPDATE_HEALTH_GRACE.
+func (d *dockerClient) waitHealthy(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, UPDATE_HEALTH_TIMEOUT)
+	defer cancel()
+
+	started := time.Now()
+	ticker := time.NewTicker(UPDATE_HEALTH_POLL)
+	defer ticker.Stop()
+
+	for {
+		inspect, err := d.cli.ContainerInspect(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to inspect new container: %v", err)
+		}
+
+		state := inspect.State
+		switch {
+		case !state.Running || state.Restarting:
+			return fmt.Errorf("new container exited with code %d", state.ExitCode)
+		case state.Health != nil && state.Health.Status == types.Unhealthy:
+			return errors.New("new container is unhealthy")
+		case state.Health != nil && state.Health.Status == types.Healthy:
+			return nil
+		case state.Health == nil && time.Since(started) >= UPDATE_HEALTH_GRACE:
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("new container did not become healthy: %v", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// healthcheckFromDocker converts a healthcheck with durations in nanoseconds to one in seconds
+func healthcheckFromDocker(healthcheck *container.HealthConfig) *ContainerHealthcheck {
+	if healthcheck == nil || len(healthcheck.Test) == 0 {
+		return nil
+	}
+	return &ContainerHealthcheck{
+		Test:        healthcheck.Test,
+		Interval:    int64(healthcheck.Interval / time.Second),
+		Timeout:     int64(healthcheck.Timeout / time.Second),
+		Retries:     healthcheck.Retries,
+		StartPeriod: int64(healthcheck.StartPeriod / time.Second),
+	}
+}
+
+// ContainerCreateConfig rebuilds the configuration a container was created with, so it can be recreated by UpdateContainer.
+// Environment variables and the command inherited from the image are left out, a newer image brings its own.
+func (d *dockerClient) ContainerCreateConfig(ctx context.Context, id string) (*ContainerCreate, error) {
+	inspect, err := d.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s: %v", id, err)
+	}
+	name := strings.TrimPrefix(inspect.Name, "/")
+
+	imageInspect, _, err := d.cli.ImageInspectWithRaw(ctx, inspect.Image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image of container %s: %v", name, err)
+	}
+
+	var imageEnv []string
+	var imageCmd []string
+	var imageHealthcheck *container.HealthConfig
+	if imageInspect.Config != nil {
+		imageEnv = imageInspect.Config.Env
+		imageCmd = imageInspect.Config.Cmd
+		imageHealthcheck = imageInspect.Config.Healthcheck
+	}
+
+	createConfig := &ContainerCreate{
+		Name:         name,
+		Image:        inspect.Config.Image,
+		Memory:       inspect.HostConfig.Resources.Memory / 1024 / 1024,
+		CPU:          float64(inspect.HostConfig.Resources.NanoCPUs) / 1e9,
+		Restart:      string(inspect.HostConfig.RestartPolicy.Name),
+		Tty:          inspect.Config.Tty,
+		AttachStdin:  inspect.Config.AttachStdin,
+		AttachStdout: inspect.Config.AttachStdout,
+		AttachStderr: inspect.Config.AttachStderr,
+	}
+
+	for _, env := range inspect.Config.Env {
+		if !slices.Contains(imageEnv, env) {
+			createConfig.Env = append(createConfig.Env, env)
+		}
+	}
+
+	if !slices.Equal(inspect.Config.Cmd, imageCmd) {
+		createConfig.Command = inspect.Config.Cmd
+	}
+
+	if healthcheck := inspect.Config.Healthcheck; healthcheck != nil && !reflect.DeepEqual(healthcheck, imageHealthcheck) {
+		createConfig.Healthcheck = healthcheckFromDocker(healthcheck)
+	}
+
+	for port, bindings := range inspect.HostConfig.PortBindings {
+		for _, binding := range bindings {
+			hostPort, err := strconv.ParseUint(binding.HostPort, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid host port %s of container %s", binding.HostPort, name)
+			}
+			createConfig.Ports = append(createConfig.Ports, PortMapping{
+				HostPort:      uint16(hostPort),
+				ContainerPort: uint16(port.Int()),
+				Protocol:      port.Proto(),
+			})
+		}
+	}
+	sort.Slice(createConfig.Ports, func(i, j int) bool {
+		return createConfig.Ports[i].ContainerPort < createConfig.Ports[j].ContainerPort
+	})
+
+	// Volumes are bound from volumeBaseDir/<container_name>/<path>, see ToDockerConfig
+	volumeDir := filepath.Join(d.volumeBaseDir, name)
+	for _, bind := range inspect.HostConfig.Binds {
+		parts := strings.Split(bind, ":")
+		if len(parts) < 2 || filepath.Join(volumeDir, parts[1]) != filepath.Clean(parts[0]) {
+			return nil, fmt.Errorf("bind %s of container %s is not a managed volume", bind, name)
+		}
+		createConfig.Volumes = append(createConfig.Volumes, parts[1])
+	}
+
+	return createConfig, nil
+}
+
+// AttachContainer attaches to the main process of a running container, streaming stdin, stdout and stderr
+func (d *dockerClient) AttachContainer(ctx context.Context, id string) (types.HijackedResponse, error) {
+	resp, err := d.cli.ContainerAttach(ctx, id, container.AttachOptions{
+		Stream: true,
+		Stdin:  true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return types.HijackedResponse{}, fmt.Errorf("failed to attach to container %s: %v", id, err)
+	}
+	return resp, nil
+}
+
+func (d *dockerClient) ResizeContainerTTY(ctx context.Context, id string, height uint, width uint) error {
+	err := d.cli.ContainerResize(ctx, id, container.ResizeOptions{Height: height, Width: width})
+	if err != nil {
+		return fmt.Errorf("failed to resize container %s: %v", id, err)
 	}
+	return nil
+}
 
-	// Create new container with updated configuration
-	id, warnings, err := d.CreateContainer(ctx, req)
+// CreateExecSession creates an interactive exec instance, it is started by AttachExecSession
+func (d *dockerClient) CreateExecSession(ctx context.Context, id string, cmd []string, tty bool) (string, error) {
+	execID, err := d.cli.ContainerExecCreate(ctx, id, container.ExecOptions{
+		Cmd:          cmd,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          tty,
+	})
 	if err != nil {
-		// Recreate the old container
-		_, err = d.cli.ContainerCreate(ctx, inspect.Config, inspect.HostConfig, nil, nil, inspect.Name)
+		return "", fmt.Errorf("failed to create exec instance %s: %v", id, err)
+	}
+	return execID.ID, nil
+}
+
+func (d *dockerClient) AttachExecSession(ctx context.Context, execID string, tty bool) (types.HijackedResponse, error) {
+	resp, err := d.cli.ContainerExecAttach(ctx, execID, container.ExecAttachOptions{Tty: tty})
+	if err != nil {
+		return types.HijackedResponse{}, fmt.Errorf("failed to attach to exec instance %s: %v", execID, err)
+	}
+	return resp, nil
+}
+
+func (d *dockerClient) ResizeExecTTY(ctx context.Context, execID string, height uint, width uint) error {
+	err := d.cli.ContainerExecResize(ctx, execID, container.ResizeOptions{Height: height, Width: width})
+	if err != nil {
+		return fmt.Errorf("failed to resize exec instance %s: %v", execID, err)
+	}
+	return nil
+}
+
+func (d *dockerClient) InspectExecSession(ctx context.Context, execID string) (*ExecInspect, error) {
+	inspect, err := d.cli.ContainerExecInspect(ctx, execID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect exec instance %s: %v", execID, err)
+	}
+	return &ExecInspect{
+		ID:          inspect.ExecID,
+		ContainerID: inspect.ContainerID,
+		Running:     inspect.Running,
+		ExitCode:    inspect.ExitCode,
+	}, nil
+}
+
+// ContainerStats returns a single stats sample, docker waits for a second sample so CPU usage can be computed
+func (d *dockerClient) ContainerStats(ctx context.Context, id string) (*ContainerStats, error) {
+	resp, err := d.cli.ContainerStats(ctx, id, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats for container %s: %v", id, err)
+	}
+	defer resp.Body.Close()
+
+	var raw container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode stats for container %s: %v", id, err)
+	}
+
+	stats := computeStats(&raw)
+	return &stats, nil
+}
+
+// StreamContainerStats emits a stats sample roughly every second until ctx is done or the container stops
+func (d *dockerClient) StreamContainerStats(ctx context.Context, id string) (<-chan ContainerStats, <-chan error) {
+	statsChan := make(chan ContainerStats)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(statsChan)
+
+		resp, err := d.cli.ContainerStats(ctx, id, true)
 		if err != nil {
-			return "", nil, fmt.Errorf("failed to recreate previous container %s: %v", id, err)
+			errChan <- fmt.Errorf("failed to get stats for container %s: %v", id, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var raw container.StatsResponse
+			if err := decoder.Decode(&raw); err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					errChan <- fmt.Errorf("failed to decode stats for container %s: %v", id, err)
+				}
+				return
+			}
+
+			select {
+			case statsChan <- computeStats(&raw):
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
 
-		return "", nil, fmt.Errorf("failed to update container %s: %v", id, err)
+	return statsChan, errChan
+}
+
+func computeStats(raw *container.StatsResponse) ContainerStats {
+	stats := ContainerStats{
+		Read:        raw.Read,
+		MemoryLimit: raw.MemoryStats.Limit,
+		Pids:        raw.PidsStats.Current,
+	}
+
+	// CPU usage relative to the whole host, scaled by the number of cores like `docker stats`
+	cpuDelta := float64(raw.CPUStats.CPUUsage.TotalUsage) - float64(raw.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(raw.CPUStats.SystemUsage) - float64(raw.PreCPUStats.SystemUsage)
+	onlineCPUs := float64(raw.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(raw.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * onlineCPUs * 100
 	}
 
-	return id, warnings, nil
+	// Page cache is reclaimable, exclude it like `docker stats` does (cgroup v1 "cache", v2 "inactive_file")
+	stats.MemoryUsage = raw.MemoryStats.Usage
+	if cache, ok := raw.MemoryStats.Stats["total_inactive_file"]; ok && cache < stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	} else if cache, ok := raw.MemoryStats.Stats["inactive_file"]; ok && cache < stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	}
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	}
+
+	for _, network := range raw.Networks {
+		stats.NetworkRx += network.RxBytes
+		stats.NetworkTx += network.TxBytes
+	}
+
+	for _, entry := range raw.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += entry.Value
+		case "write":
+			stats.BlockWrite += entry.Value
+		}
+	}
+
+	return stats
 }
 
 func IsHeaderPresent(line []byte) bool {
@@ -388,6 +891,13 @@ func (r *ContainerCreate) ToDockerConfig(volumeBaseDir string) (*container.Confi
 		}
 	}
 
+	// Volumes of a container must stay in its own directory, a name or volume escaping it would
+	// bind mount the host or the volumes of another container
+	volumeDir := filepath.Join(volumeBaseDir, r.Name)
+	if r.Name == "" || filepath.Dir(volumeDir) != filepath.Clean(volumeBaseDir) {
+		return nil, nil, fmt.Errorf("invalid container name %q", r.Name)
+	}
+
 	// Create volume bindings
 	var volumes map[string]struct{}
 	var binds []string
@@ -395,7 +905,10 @@ func (r *ContainerCreate) ToDockerConfig(volumeBaseDir string) (*container.Confi
 		volumes = make(map[string]struct{})
 		for _, volume := range r.Volumes {
 			// Create the host path as /volumeBaseDir/<container_name>/<path>
-			hostPath := filepath.Join(volumeBaseDir, r.Name, volume)
+			hostPath := filepath.Join(volumeDir, volume)
+			if hostPath == volumeDir || !strings.HasPrefix(hostPath, volumeDir+string(filepath.Separator)) {
+				return nil, nil, fmt.Errorf("invalid volume %q, it must be a path inside the container's volume directory", volume)
+			}
 			// Use the original path as container path
 			containerPath := filepath.Join("/", volume)
 
@@ -414,11 +927,20 @@ func (r *ContainerCreate) ToDockerConfig(volumeBaseDir string) (*container.Confi
 		ExposedPorts: exposedPorts,
 		Volumes:      volumes,
 		Tty:          r.Tty,
+		OpenStdin:    r.AttachStdin, // Keep stdin open so the console can be attached to later
 		AttachStdin:  r.AttachStdin,
 		AttachStdout: r.AttachStdout,
 		AttachStderr: r.AttachStderr,
 	}
 
+	if r.Healthcheck != nil {
+		healthcheck, err := r.Healthcheck.toDocker()
+		if err != nil {
+			return nil, nil, err
+		}
+		config.Healthcheck = healthcheck
+	}
+
 	// Create host config
 	hostConfig := &container.HostConfig{
 		PortBindings: portBindings,
@@ -440,3 +962,33 @@ func (r *ContainerCreate) ToDockerConfig(volumeBaseDir string) (*container.Confi
 
 	return config, hostConfig, nil
 }
+
+// toDocker validates the healthcheck and converts it to Docker's format, durations of zero use Docker's defaults
+func (h *ContainerHealthcheck) toDocker() (*container.HealthConfig, error) {
+	if len(h.Test) == 0 {
+		return nil, errors.New("healthcheck test is required")
+	}
+
+	switch h.Test[0] {
+	case "NONE":
+		return &container.HealthConfig{Test: []string{"NONE"}}, nil
+	case "CMD", "CMD-SHELL":
+		if len(h.Test) < 2 || strings.TrimSpace(strings.Join(h.Test[1:], "")) == "" {
+			return nil, errors.New("healthcheck test has no command")
+		}
+	default:
+		return nil, fmt.Errorf("healthcheck test must start with CMD, CMD-SHELL or NONE, got %s", h.Test[0])
+	}
+
+	if h.Interval < 0 || h.Timeout < 0 || h.Retries < 0 || h.StartPeriod < 0 {
+		return nil, errors.New("healthcheck durations and retries must not be negative")
+	}
+
+	return &container.HealthConfig{
+		Test:        h.Test,
+		Interval:    time.Duration(h.Interval) * time.Second,
+		Timeout:     time.Duration(h.Timeout) * time.Second,
+		Retries:     h.Retries,
+		StartPeriod: time.Duration(h.StartPeriod) * time.Second,
+	}, nil
+}
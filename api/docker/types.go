@@ -1,15 +1,18 @@
 package docker
 
 import (
+	"gsm/query"
 	"time"
 )
 
 type ContainerListItem struct {
-	ID     string   `json:"id"`
-	Names  []string `json:"names"`
-	Image  string   `json:"image"`
-	State  string   `json:"state"`
-	Status string   `json:"status"`
+	ID              string   `json:"id"`
+	Names           []string `json:"names"`
+	Image           string   `json:"image"`
+	ImageID         string   `json:"imageId"`
+	State           string   `json:"state"`
+	Status          string   `json:"status"`
+	UpdateAvailable bool     `json:"updateAvailable"` // The registry has a newer image for the container's tag
 }
 
 type ContainerInspect struct {
@@ -20,7 +23,8 @@ type ContainerInspect struct {
 	Mounts      []Mount             `json:"mounts"`
 	Config      ContainerConfig     `json:"config"`
 	HostConfig  ContainerHostConfig `json:"hostConfig"`
-	Connections map[string]int      `json:"connections"` // hostport/protocol -> nb of connections
+	Connections map[string]int      `json:"connections"`     // hostport/protocol -> nb of connections
+	Query       *query.Info         `json:"query,omitempty"` // Live server status, set when a query protocol is configured
 }
 
 type Mount struct {
@@ -31,21 +35,46 @@ type Mount struct {
 }
 
 type ContainerState struct {
-	Status     string    `json:"status"`
-	Running    bool      `json:"running"`
-	StartedAt  time.Time `json:"startedAt"`
-	FinishedAt time.Time `json:"finishedAt"`
+	Status     string           `json:"status"`
+	Running    bool             `json:"running"`
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt time.Time        `json:"finishedAt"`
+	Health     *ContainerHealth `json:"health,omitempty"` // Set for containers with a healthcheck
+}
+
+type ContainerHealth struct {
+	Status        string        `json:"status"`        // starting, healthy or unhealthy
+	FailingStreak int           `json:"failingStreak"` // Consecutive failed probes
+	Log           []HealthProbe `json:"log"`           // Most recent probes, oldest first
+}
+
+type HealthProbe struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	ExitCode int       `json:"exitCode"`
+	Output   string    `json:"output"`
+}
+
+// ContainerHealthcheck configures the probe Docker runs inside the container, durations are in seconds
+type ContainerHealthcheck struct {
+	Test        []string `json:"test" binding:"required"` // e.g. ["CMD-SHELL", "curl -f http://localhost/"] or ["NONE"] to disable the image's healthcheck
+	Interval    int64    `json:"interval" binding:"gte=0"`
+	Timeout     int64    `json:"timeout" binding:"gte=0"`
+	Retries     int      `json:"retries" binding:"gte=0"`
+	StartPeriod int64    `json:"startPeriod" binding:"gte=0"` // Failures during the start period do not count towards retries
 }
 
 type ContainerConfig struct {
-	Image        string              `json:"image"`
-	Env          []string            `json:"env"`
-	Tty          bool                `json:"tty"`
-	AttachStdin  bool                `json:"attachStdin"`
-	AttachStdout bool                `json:"attachStdout"`
-	AttachStderr bool                `json:"attachStderr"`
-	ExposedPorts map[string]struct{} `json:"exposedPorts"`
-	Volumes      map[string]struct{} `json:"volumes"`
+	Image        string                `json:"image"`
+	Env          []string              `json:"env"`
+	Tty          bool                  `json:"tty"`
+	OpenStdin    bool                  `json:"openStdin"`
+	AttachStdin  bool                  `json:"attachStdin"`
+	AttachStdout bool                  `json:"attachStdout"`
+	AttachStderr bool                  `json:"attachStderr"`
+	ExposedPorts map[string]struct{}   `json:"exposedPorts"`
+	Volumes      map[string]struct{}   `json:"volumes"`
+	Healthcheck  *ContainerHealthcheck `json:"healthcheck,omitempty"`
 }
 
 type ContainerHostConfig struct {
@@ -68,19 +97,20 @@ type ContainerPortBinding struct {
 }
 
 type ContainerCreate struct {
-	Name         string        `json:"name" binding:"required"`
-	Image        string        `json:"image" binding:"required"`
-	Ports        []PortMapping `json:"ports"`
-	Env          []string      `json:"env"`
-	Memory       int64         `json:"memory" binding:"gte=0"`
-	CPU          float64       `json:"cpu" binding:"gte=0"`
-	Command      []string      `json:"command"`
-	Restart      string        `json:"restart" binding:"oneof=no on-failure always unless-stopped"`
-	Volumes      []string      `json:"volumes"`
-	Tty          bool          `json:"tty"`
-	AttachStdin  bool          `json:"attachStdin"`
-	AttachStdout bool          `json:"attachStdout"`
-	AttachStderr bool          `json:"attachStderr"`
+	Name         string                `json:"name" binding:"required"`
+	Image        string                `json:"image" binding:"required"`
+	Ports        []PortMapping         `json:"ports"`
+	Env          []string              `json:"env"`
+	Memory       int64                 `json:"memory" binding:"gte=0"`
+	CPU          float64               `json:"cpu" binding:"gte=0"`
+	Command      []string              `json:"command"`
+	Restart      string                `json:"restart" binding:"oneof=no on-failure always unless-stopped"`
+	Volumes      []string              `json:"volumes"`
+	Tty          bool                  `json:"tty"`
+	AttachStdin  bool                  `json:"attachStdin"`
+	AttachStdout bool                  `json:"attachStdout"`
+	AttachStderr bool                  `json:"attachStderr"`
+	Healthcheck  *ContainerHealthcheck `json:"healthcheck,omitempty"`
 }
 
 type ContainerRestartPolicy struct {
@@ -92,3 +122,23 @@ type PortMapping struct {
 	ContainerPort uint16 `json:"containerPort" binding:"required,gt=0"`
 	Protocol      string `json:"protocol" binding:"oneof=tcp udp"`
 }
+
+type ExecInspect struct {
+	ID          string `json:"id"`
+	ContainerID string `json:"containerId"`
+	Running     bool   `json:"running"`
+	ExitCode    int    `json:"exitCode"`
+}
+
+type ContainerStats struct {
+	Read          time.Time `json:"read"`
+	CPUPercent    float64   `json:"cpuPercent"`    // Percentage of a single core, can exceed 100 on multi-core hosts
+	MemoryUsage   uint64    `json:"memoryUsage"`   // Bytes, excluding page cache
+	MemoryLimit   uint64    `json:"memoryLimit"`   // Bytes
+	MemoryPercent float64   `json:"memoryPercent"` // Usage relative to the limit
+	NetworkRx     uint64    `json:"networkRx"`     // Bytes received on all networks
+	NetworkTx     uint64    `json:"networkTx"`     // Bytes sent on all networks
+	BlockRead     uint64    `json:"blockRead"`     // Bytes read from block devices
+	BlockWrite    uint64    `json:"blockWrite"`    // Bytes written to block devices
+	Pids          uint64    `json:"pids"`          // Number of processes
+}
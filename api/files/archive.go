@@ -0,0 +1,619 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+const ARCHIVE_SNIFF_SIZE = 512 // Enough to find the tar magic at offset 257
+
+var (
+	magicZip   = []byte("PK\x03\x04")
+	magicEmpty = []byte("PK\x05\x06") // Zip without entries
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicTar   = []byte("ustar")
+)
+
+// ArchiveFormatFromName returns the format matching the file name's extension
+func ArchiveFormatFromName(name string) (ArchiveFormat, bool) {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveFormatZip, true
+	case strings.HasSuffix(name, ".tar"):
+		return ArchiveFormatTar, true
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveFormatTarGz, true
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return ArchiveFormatTarZst, true
+	}
+	return "", false
+}
+
+// IsValidArchiveFormat reports whether format is one of the supported archive formats
+func IsValidArchiveFormat(format ArchiveFormat) bool {
+	switch format {
+	case ArchiveFormatZip, ArchiveFormatTar, ArchiveFormatTarGz, ArchiveFormatTarZst:
+		return true
+	}
+	return false
+}
+
+// detectArchiveFormat identifies an archive by its content, the file extension is not trusted
+func detectArchiveFormat(file io.ReadSeeker) (ArchiveFormat, error) {
+	header := make([]byte, ARCHIVE_SNIFF_SIZE)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read archive: %v", err)
+	}
+	header = header[:n]
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to read archive: %v", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(header, magicZip), bytes.HasPrefix(header, magicEmpty):
+		return ArchiveFormatZip, nil
+	case bytes.HasPrefix(header, magicGzip):
+		return ArchiveFormatTarGz, nil
+	case bytes.HasPrefix(header, magicZstd):
+		return ArchiveFormatTarZst, nil
+	case len(header) >= 262 && bytes.Equal(header[257:262], magicTar):
+		return ArchiveFormatTar, nil
+	}
+	return "", errors.New("unsupported archive format, expected zip, tar, tar.gz or tar.zst")
+}
+
+// Extract unpacks the archive at source into the destination directory, overwriting existing files.
+// Entry paths are confined to the destination, links and special files are skipped.
+// When extraction fails, files and directories it created are removed again.
+func (f *fileClient) Extract(source, destination string, limits ArchiveLimits, progress func(ArchiveProgress)) error {
+	sourcePath, err := f.sanitizePath(source)
+	if err != nil {
+		return err
+	}
+	destPath, err := f.sanitizePath(destination)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %v", err)
+	}
+	defer file.Close()
+
+	format, err := detectArchiveFormat(file)
+	if err != nil {
+		return err
+	}
+
+	x := &extractor{root: filepath.Clean(f.baseDir), destination: destPath, limits: limits, progress: progress}
+	if err := x.mkdir(destPath); err != nil {
+		return err
+	}
+
+	switch format {
+	case ArchiveFormatZip:
+		err = x.extractZip(file)
+	case ArchiveFormatTar:
+		err = x.extractTar(file)
+	case ArchiveFormatTarGz:
+		var reader *gzip.Reader
+		if reader, err = gzip.NewReader(file); err == nil {
+			err = x.extractTar(reader)
+			reader.Close()
+		}
+	case ArchiveFormatTarZst:
+		var reader *zstd.Decoder
+		if reader, err = zstd.NewReader(file); err == nil {
+			err = x.extractTar(reader)
+			reader.Close()
+		}
+	}
+
+	if err != nil {
+		x.cleanup()
+		return fmt.Errorf("failed to extract archive: %v", err)
+	}
+	return nil
+}
+
+type extractor struct {
+	root        string // Base directory, no directory below it may be a link
+	destination string
+	limits      ArchiveLimits
+	progress    func(ArchiveProgress)
+	state       ArchiveProgress
+	created     []string // Paths that did not exist before, in creation order
+}
+
+func (x *extractor) extractZip(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range reader.File {
+		mode := entry.Mode()
+		switch {
+		case mode.IsDir():
+			err = x.dir(entry.Name)
+		case mode.IsRegular():
+			err = x.zipFile(entry)
+		default:
+			err = x.skip(entry.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) zipFile(entry *zip.File) error {
+	reader, err := entry.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", entry.Name, err)
+	}
+	defer reader.Close()
+	return x.file(entry.Name, entry.Mode(), reader)
+}
+
+func (x *extractor) extractTar(r io.Reader) error {
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = x.dir(header.Name)
+		case tar.TypeReg:
+			err = x.file(header.Name, header.FileInfo().Mode(), reader)
+		case tar.TypeXGlobalHeader:
+			continue
+		default:
+			err = x.skip(header.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// target returns where an entry is extracted to, ".." components cannot climb above the destination
+func (x *extractor) target(name string) (string, error) {
+	target := filepath.Join(x.destination, filepath.FromSlash(path.Clean("/"+name)))
+	if target != x.destination && !strings.HasPrefix(target, x.destination+string(filepath.Separator)) {
+		return "", fmt.Errorf("entry %s is outside the destination", name)
+	}
+	return target, nil
+}
+
+// next counts an entry against the entry limit
+func (x *extractor) next(name string) error {
+	x.state.Entries++
+	x.state.Entry = name
+	x.state.Skipped = false
+	if x.limits.MaxEntries > 0 && x.state.Entries > x.limits.MaxEntries {
+		return fmt.Errorf("archive has more than %d entries", x.limits.MaxEntries)
+	}
+	return nil
+}
+
+func (x *extractor) report() {
+	if x.progress != nil {
+		x.progress(x.state)
+	}
+}
+
+func (x *extractor) dir(name string) error {
+	if err := x.next(name); err != nil {
+		return err
+	}
+	target, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	if err := x.mkdir(target); err != nil {
+		return err
+	}
+	x.report()
+	return nil
+}
+
+func (x *extractor) file(name string, mode os.FileMode, r io.Reader) error {
+	if err := x.next(name); err != nil {
+		return err
+	}
+	target, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	if err := x.mkdir(filepath.Dir(target)); err != nil {
+		return err
+	}
+
+	if info, err := os.Lstat(target); os.IsNotExist(err) {
+		x.created = append(x.created, target)
+	} else if err == nil && info.Mode()&os.ModeSymlink != 0 {
+		// Writing would follow the link, possibly out of the volume
+		return fmt.Errorf("entry %s would overwrite a link", name)
+	}
+
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", name, err)
+	}
+	defer dst.Close()
+
+	// Sizes in archive headers can lie, the limit is enforced on the bytes actually written
+	reader := r
+	if x.limits.MaxSize > 0 {
+		reader = io.LimitReader(r, x.limits.MaxSize-x.state.Bytes+1)
+	}
+	written, err := io.Copy(dst, reader)
+	x.state.Bytes += written
+	if x.limits.MaxSize > 0 && x.state.Bytes > x.limits.MaxSize {
+		return fmt.Errorf("archive is larger than %d bytes when extracted", x.limits.MaxSize)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+
+	x.report()
+	return nil
+}
+
+func (x *extractor) skip(name string) error {
+	if err := x.next(name); err != nil {
+		return err
+	}
+	x.state.Skipped = true
+	x.report()
+	return nil
+}
+
+// mkdir creates a directory and its parents below the root, remembering the ones that did not exist.
+// Existing components must be real directories, a link planted in a volume could otherwise lead out of it.
+func (x *extractor) mkdir(dir string) error {
+	rel, err := filepath.Rel(x.root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("directory %s is outside the base directory", dir)
+	}
+	if rel == "." {
+		return nil
+	}
+
+	current := x.root
+	exists := true
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+
+		if exists {
+			info, err := os.Lstat(current)
+			switch {
+			case os.IsNotExist(err):
+				exists = false
+			case err != nil:
+				return fmt.Errorf("failed to create directory: %v", err)
+			case info.Mode()&os.ModeSymlink != 0:
+				return fmt.Errorf("%s is a link", x.relative(current))
+			case !info.IsDir():
+				return fmt.Errorf("%s is not a directory", x.relative(current))
+			default:
+				continue
+			}
+		}
+
+		if err := os.Mkdir(current, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %v", err)
+		}
+		x.created = append(x.created, current)
+	}
+	return nil
+}
+
+// relative returns a path as shown to users, relative to the destination
+func (x *extractor) relative(target string) string {
+	rel, err := filepath.Rel(x.destination, target)
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
+
+// cleanup removes what the extraction created, newest first so directories are empty by then
+func (x *extractor) cleanup() {
+	for i := len(x.created) - 1; i >= 0; i-- {
+		os.Remove(x.created[i])
+	}
+}
+
+// Compress archives the sources into a new archive file at destination, each source is stored under its base name
+func (f *fileClient) Compress(sources []string, destination string, format ArchiveFormat, progress func(ArchiveProgress)) error {
+	if len(sources) == 0 {
+		return errors.New("nothing to compress")
+	}
+
+	destPath, err := f.sanitizePath(destination)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]archiveSource, len(sources))
+	for i, source := range sources {
+		sourcePath, err := f.sanitizePath(source)
+		if err != nil {
+			return err
+		}
+		if sourcePath == f.baseDir {
+			return errors.New("cannot compress the root directory")
+		}
+		if _, err := os.Stat(sourcePath); err != nil {
+			return fmt.Errorf("failed to find %s: %v", source, err)
+		}
+		entries[i] = archiveSource{path: sourcePath, name: filepath.Base(sourcePath)}
+	}
+
+	// Write to a temporary file first so a failed archive never shows up under its name
+	tmp, err := os.CreateTemp(filepath.Dir(destPath), ".archive-*")
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeArchive(tmp, format, entries, archiveFilter{skip: tmp.Name()}, progress); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write archive: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write archive: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), destPath); err != nil {
+		return fmt.Errorf("failed to save archive: %v", err)
+	}
+	return nil
+}
+
+// archiveSource is a file or directory added to an archive under name, an empty name adds a directory's contents
+type archiveSource struct {
+	path string
+	name string
+}
+
+// archiveWriter writes entries in one of the archive formats
+type archiveWriter interface {
+	dir(name string, info os.FileInfo) error
+	file(name string, info os.FileInfo, r io.Reader) error
+	Close() error
+}
+
+func newArchiveWriter(w io.Writer, format ArchiveFormat) (archiveWriter, error) {
+	switch format {
+	case ArchiveFormatZip:
+		return &zipArchive{writer: zip.NewWriter(w)}, nil
+	case ArchiveFormatTar:
+		return &tarArchive{writer: tar.NewWriter(w)}, nil
+	case ArchiveFormatTarGz:
+		compressor := gzip.NewWriter(w)
+		return &tarArchive{writer: tar.NewWriter(compressor), compressor: compressor}, nil
+	case ArchiveFormatTarZst:
+		compressor, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		return &tarArchive{writer: tar.NewWriter(compressor), compressor: compressor}, nil
+	}
+	return nil, fmt.Errorf("unsupported archive format %s", format)
+}
+
+// archiveFilter leaves paths out of an archive
+type archiveFilter struct {
+	skip     string   // Absolute path, e.g. the archive being written
+	excludes []string // Patterns matched against paths relative to their source, see matchExclude
+}
+
+func (f archiveFilter) excluded(filePath, relPath string) bool {
+	if filePath == f.skip {
+		return true
+	}
+	// A selected path is never excluded by its own patterns
+	if relPath == "." {
+		return false
+	}
+	for _, pattern := range f.excludes {
+		if matchExclude(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkArchive calls visit with the archive name of every path under the sources that is not filtered out,
+// excluded directories are not descended into
+func walkArchive(sources []archiveSource, filter archiveFilter, visit func(name, filePath string, info os.FileInfo) error) error {
+	for _, source := range sources {
+		err := filepath.Walk(source.path, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			relPath, err := filepath.Rel(source.path, filePath)
+			if err != nil {
+				return err
+			}
+			relPath = filepath.ToSlash(relPath)
+
+			if filter.excluded(filePath, relPath) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			name := path.Join(source.name, relPath)
+			if name == "." {
+				return nil
+			}
+			return visit(name, filePath, info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeArchive walks the sources into an archive
+func writeArchive(w io.Writer, format ArchiveFormat, sources []archiveSource, filter archiveFilter, progress func(ArchiveProgress)) error {
+	archive, err := newArchiveWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	var state ArchiveProgress
+	err = walkArchive(sources, filter, func(name, filePath string, info os.FileInfo) error {
+		state.Entries++
+		state.Entry = name
+		state.Skipped = false
+
+		var err error
+		switch {
+		case info.IsDir():
+			err = archive.dir(name, info)
+		case info.Mode().IsRegular():
+			err = addArchiveFile(archive, name, info, filePath)
+			state.Bytes += info.Size()
+		default:
+			state.Skipped = true
+		}
+		if err != nil {
+			return err
+		}
+
+		if progress != nil {
+			progress(state)
+		}
+		return nil
+	})
+	if err != nil {
+		archive.Close()
+		return err
+	}
+
+	return archive.Close()
+}
+
+func addArchiveFile(archive archiveWriter, name string, info os.FileInfo, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return archive.file(name, info, file)
+}
+
+type zipArchive struct {
+	writer *zip.Writer
+}
+
+func (a *zipArchive) dir(name string, info os.FileInfo) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name + "/"
+	_, err = a.writer.CreateHeader(header)
+	return err
+}
+
+func (a *zipArchive) file(name string, info os.FileInfo, r io.Reader) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	writer, err := a.writer.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, r)
+	return err
+}
+
+func (a *zipArchive) Close() error {
+	return a.writer.Close()
+}
+
+type tarArchive struct {
+	writer     *tar.Writer
+	compressor io.WriteCloser // Nil for plain tar
+}
+
+func (a *tarArchive) dir(name string, info os.FileInfo) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name + "/"
+	return a.writer.WriteHeader(header)
+}
+
+func (a *tarArchive) file(name string, info os.FileInfo, r io.Reader) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+
+	if err := a.writer.WriteHeader(header); err != nil {
+		return err
+	}
+	// The header announces the size, a file that grew meanwhile is cut off there
+	_, err = io.Copy(a.writer, io.LimitReader(r, header.Size))
+	return err
+}
+
+func (a *tarArchive) Close() error {
+	err := a.writer.Close()
+	if a.compressor != nil {
+		if closeErr := a.compressor.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
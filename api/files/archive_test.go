@@ -0,0 +1,104 @@
+package files
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTar creates a tar archive at path holding the given entries, names ending in "/" are directories
+func writeTar(t *testing.T, path string, entries []string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := tar.NewWriter(file)
+	for _, name := range entries {
+		header := &tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(name))}
+		if name[len(name)-1] == '/' {
+			header = &tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := writer.Write([]byte(name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractRejectsLinkedDirectories(t *testing.T) {
+	base := t.TempDir()
+	outside := t.TempDir()
+
+	volume := filepath.Join(base, "volume")
+	if err := os.Mkdir(volume, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(volume, "x")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		destination string
+		entries     []string
+	}{
+		{"file below link", "/volume", []string{"x/escaped.txt"}},
+		{"directory below link", "/volume", []string{"x/sub/"}},
+		{"nested file below link", "/volume", []string{"ok/", "x/sub/escaped.txt"}},
+		{"destination below link", "/volume/x", []string{"escaped.txt"}},
+	}
+
+	client := NewClient(base)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeTar(t, filepath.Join(base, "archive.tar"), tt.entries)
+
+			if err := client.Extract("/archive.tar", tt.destination, ArchiveLimits{}, nil); err == nil {
+				t.Fatal("expected extraction through a link to fail")
+			}
+
+			escaped, err := os.ReadDir(outside)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(escaped) != 0 {
+				t.Fatalf("extraction wrote %d entries outside the volume", len(escaped))
+			}
+			if _, err := os.Lstat(filepath.Join(volume, "ok")); !os.IsNotExist(err) {
+				t.Fatal("directories created by the failed extraction were not removed")
+			}
+		})
+	}
+}
+
+func TestExtractCreatesDirectories(t *testing.T) {
+	base := t.TempDir()
+	writeTar(t, filepath.Join(base, "archive.tar"), []string{"a/", "a/b/c.txt", "d.txt"})
+
+	client := NewClient(base)
+	if err := client.Extract("/archive.tar", "/volume/out", ArchiveLimits{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a/b/c.txt", "d.txt"} {
+		data, err := os.ReadFile(filepath.Join(base, "volume", "out", filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != name {
+			t.Fatalf("%s holds %q", name, data)
+		}
+	}
+}
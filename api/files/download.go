@@ -0,0 +1,113 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DownloadArchive streams an archive of the selected paths to writer. A single directory is archived by its
+// contents, several paths are each stored under their base name. Paths are checked before anything is written.
+func (f *fileClient) DownloadArchive(paths []string, excludes []string, format ArchiveFormat, writer io.Writer) error {
+	sources, err := f.archiveSelection(paths, excludes)
+	if err != nil {
+		return err
+	}
+	return writeArchive(writer, format, sources, archiveFilter{excludes: excludes}, nil)
+}
+
+// SummarizeArchive counts what DownloadArchive would write without reading any file
+func (f *fileClient) SummarizeArchive(paths []string, excludes []string) (ArchiveSummary, error) {
+	var summary ArchiveSummary
+
+	sources, err := f.archiveSelection(paths, excludes)
+	if err != nil {
+		return summary, err
+	}
+
+	err = walkArchive(sources, archiveFilter{excludes: excludes}, func(name, filePath string, info os.FileInfo) error {
+		switch {
+		case info.IsDir():
+			summary.Directories++
+		case info.Mode().IsRegular():
+			summary.Files++
+			summary.Size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return summary, fmt.Errorf("failed to read files: %v", err)
+	}
+	return summary, nil
+}
+
+// archiveSelection validates the selected paths and exclude patterns
+func (f *fileClient) archiveSelection(paths []string, excludes []string) ([]archiveSource, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("path is required")
+	}
+
+	for _, pattern := range excludes {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return nil, fmt.Errorf("invalid exclude pattern %q", pattern)
+		}
+	}
+
+	sources := make([]archiveSource, len(paths))
+	for i, p := range paths {
+		fullPath, err := f.sanitizePath(p)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find %s: %v", p, err)
+		}
+
+		sources[i] = archiveSource{path: fullPath, name: filepath.Base(fullPath)}
+		if len(paths) == 1 && info.IsDir() {
+			sources[i].name = ""
+		} else if fullPath == f.baseDir {
+			return nil, errors.New("the root directory can only be downloaded on its own")
+		}
+	}
+	return sources, nil
+}
+
+// matchExclude reports whether an exclude pattern matches a slash separated relative path. Patterns
+// without a slash match the base name at any depth, so "*.tmp" excludes every temporary file, and
+// a "**" segment matches any number of directories, so "logs/**" excludes the logs directory.
+func matchExclude(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(name))
+		return matched
+	}
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -1,7 +1,7 @@
 package files
 
 import (
-	"archive/zip"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -19,7 +19,14 @@ type Client interface {
 	DeletePath(path string) error
 	MovePath(source, destination string) error
 	DownloadFile(path string, writer io.Writer) error
+	OpenFile(path string) (*os.File, os.FileInfo, error)
+	DownloadArchive(paths []string, excludes []string, format ArchiveFormat, writer io.Writer) error
+	SummarizeArchive(paths []string, excludes []string) (ArchiveSummary, error)
+	Search(ctx context.Context, options SearchOptions, found func(SearchResult)) (SearchSummary, error)
 	UploadFile(destination string, filename string, file io.Reader) error
+	FilePath(directory string, filename string) (string, error)
+	Extract(source, destination string, limits ArchiveLimits, progress func(ArchiveProgress)) error
+	Compress(sources []string, destination string, format ArchiveFormat, progress func(ArchiveProgress)) error
 }
 
 type fileClient struct {
@@ -201,7 +208,7 @@ func (f *fileClient) DownloadFile(path string, writer io.Writer) error {
 	}
 
 	if info.IsDir() {
-		return createZipFromDir(writer, fullPath)
+		return writeArchive(writer, ArchiveFormatZip, []archiveSource{{path: fullPath}}, archiveFilter{}, nil)
 	}
 
 	file, err := os.Open(fullPath)
@@ -214,6 +221,31 @@ func (f *fileClient) DownloadFile(path string, writer io.Writer) error {
 	return err
 }
 
+// OpenFile opens a file or directory for reading, the caller closes it
+func (f *fileClient) OpenFile(path string) (*os.File, os.FileInfo, error) {
+	if path == "" {
+		return nil, nil, fmt.Errorf("path is required")
+	}
+
+	fullPath, err := f.sanitizePath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file: %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to get file info: %v", err)
+	}
+
+	return file, info, nil
+}
+
 func (f *fileClient) UploadFile(destination string, filename string, file io.Reader) error {
 	fullPath, err := f.sanitizePath(destination)
 	if err != nil {
@@ -235,31 +267,26 @@ func (f *fileClient) UploadFile(destination string, filename string, file io.Rea
 	}
 	defer dst.Close()
 
-	// Copy the uploaded file
+	// Copy the uploaded file, archives are kept as they are and extracted on request
 	_, err = io.Copy(dst, file)
 	if err != nil {
 		return fmt.Errorf("failed to save file: %v", err)
 	}
 
-	// Check if the file is a zip file
-	mime, err := mimetype.DetectFile(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to detect file type: %v", err)
-	}
+	return nil
+}
 
-	if mime.String() == "application/zip" {
-		// Extract the zip file
-		err = extractZip(filePath, fullPath)
-		if err != nil {
-			// Clean up the zip file if extraction fails
-			os.Remove(filePath)
-			return fmt.Errorf("failed to extract zip file: %v", err)
-		}
-		// Remove the original zip file after successful extraction
-		os.Remove(filePath)
+// FilePath returns the full path of filename in directory, filename has to be a plain file name
+func (f *fileClient) FilePath(directory string, filename string) (string, error) {
+	if filename == "" || filename == "." || filename == ".." || strings.ContainsAny(filename, `/\`) {
+		return "", fmt.Errorf("invalid file name %q", filename)
 	}
 
-	return nil
+	fullPath, err := f.sanitizePath(directory)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(fullPath, filename), nil
 }
 
 func (f *fileClient) sanitizePath(requestPath string) (string, error) {
@@ -284,106 +311,3 @@ func getFilePermissions(info os.FileInfo) (bool, bool, bool) {
 	isExecutable := mode&0111 != 0 // Check if executable
 	return isReadable, isWritable, isExecutable
 }
-
-func createZipFromDir(writer io.Writer, dir string) error {
-	zipWriter := zip.NewWriter(writer)
-	defer zipWriter.Close()
-
-	// Walk through the directory
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Create a zip header
-		header, err := zip.FileInfoHeader(info)
-		if err != nil {
-			return err
-		}
-
-		// Set the header name to be relative to the directory being zipped
-		relPath, err := filepath.Rel(dir, path)
-		if err != nil {
-			return err
-		}
-		if relPath == "." {
-			return nil
-		}
-		header.Name = relPath
-
-		if info.IsDir() {
-			header.Name += "/"
-		} else {
-			header.Method = zip.Deflate
-		}
-
-		writer, err := zipWriter.CreateHeader(header)
-		if err != nil {
-			return err
-		}
-
-		if !info.IsDir() {
-			file, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-			defer file.Close()
-			_, err = io.Copy(writer, file)
-			if err != nil {
-				return err
-			}
-		}
-		return err
-	})
-
-	return err
-}
-
-func extractZip(zipFile string, destination string) error {
-	reader, err := zip.OpenReader(zipFile)
-	if err != nil {
-		return err
-	}
-	defer reader.Close()
-
-	for _, file := range reader.File {
-		// Sanitize file path to prevent zip slip
-		filePath := filepath.Join(destination, file.Name)
-		if !strings.HasPrefix(filePath, destination) {
-			continue // Skip files that would be extracted outside destination
-		}
-
-		if file.FileInfo().IsDir() {
-			os.MkdirAll(filePath, 0755)
-			continue
-		}
-
-		// Create directory for file if it doesn't exist
-		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-			return err
-		}
-
-		// Create file
-		dstFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
-		if err != nil {
-			return err
-		}
-
-		// Open file in zip
-		srcFile, err := file.Open()
-		if err != nil {
-			dstFile.Close()
-			return err
-		}
-
-		// Copy contents
-		_, err = io.Copy(dstFile, srcFile)
-		dstFile.Close()
-		srcFile.Close()
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
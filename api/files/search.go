@@ -0,0 +1,247 @@
+package files
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	SEARCH_SNIFF_SIZE  = 8000    // Files with a NUL byte in their first bytes are treated as binary, like git does
+	SEARCH_LINE_LENGTH = 256     // Matching lines are cut to this many bytes
+	SEARCH_MAX_LINE    = 1 << 20 // Files with longer lines are not searched
+)
+
+var errSearchLimit = errors.New("search limit reached")
+
+// Search walks the option paths and calls found for every match, stopping at MaxResults or when ctx is done.
+// Symbolic links are neither followed nor searched. Only a cancelled context is returned as an error,
+// a passed deadline ends the search with TimedOut set.
+func (f *fileClient) Search(ctx context.Context, options SearchOptions, found func(SearchResult)) (SearchSummary, error) {
+	var summary SearchSummary
+
+	s, err := newSearcher(options)
+	if err != nil {
+		return summary, err
+	}
+
+	roots := make([]string, len(options.Paths))
+	for i, p := range options.Paths {
+		if roots[i], err = f.sanitizePath(p); err != nil {
+			return summary, err
+		}
+	}
+
+	for _, root := range roots {
+		err = filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil {
+				// Unreadable directories are left out rather than failing the whole search
+				if entry != nil && entry.IsDir() && filePath != root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if filePath == root {
+				return nil
+			}
+
+			result, ok, err := s.match(ctx, filePath, entry, &summary)
+			if err != nil || !ok {
+				return err
+			}
+
+			relPath, err := filepath.Rel(f.baseDir, filePath)
+			if err != nil {
+				return err
+			}
+			result.Path = filepath.ToSlash(relPath)
+
+			summary.Files++
+			summary.Matches += len(result.Matches)
+			found(result)
+
+			if s.maxResults > 0 && s.results(summary) >= s.maxResults {
+				summary.Truncated = true
+				return errSearchLimit
+			}
+			return nil
+		})
+		switch {
+		case errors.Is(err, errSearchLimit):
+			return summary, nil
+		case errors.Is(err, context.DeadlineExceeded):
+			summary.TimedOut = true
+			return summary, nil
+		case err != nil:
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// Validate checks the name and content patterns
+func (o SearchOptions) Validate() error {
+	_, err := newSearcher(o)
+	return err
+}
+
+type searcher struct {
+	options    SearchOptions
+	name       string
+	content    *regexp.Regexp
+	maxResults int
+}
+
+func newSearcher(options SearchOptions) (*searcher, error) {
+	s := &searcher{options: options, name: options.Name, maxResults: options.MaxResults}
+
+	if s.name != "" {
+		if options.IgnoreCase {
+			s.name = strings.ToLower(s.name)
+		}
+		if _, err := path.Match(s.name, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q", options.Name)
+		}
+	}
+
+	if options.Content != "" {
+		expr := options.Content
+		if !options.Regex {
+			expr = regexp.QuoteMeta(expr)
+		}
+		if options.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+
+		content, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content pattern: %v", err)
+		}
+		s.content = content
+	}
+
+	return s, nil
+}
+
+// results counts what MaxResults limits
+func (s *searcher) results(summary SearchSummary) int {
+	if s.content != nil {
+		return summary.Matches
+	}
+	return summary.Files
+}
+
+// match applies the filters to a walked entry, searching its content last since it is the most expensive
+func (s *searcher) match(ctx context.Context, filePath string, entry fs.DirEntry, summary *SearchSummary) (SearchResult, bool, error) {
+	result := SearchResult{Name: entry.Name(), IsDir: entry.IsDir()}
+
+	if s.name != "" {
+		name := entry.Name()
+		if s.options.IgnoreCase {
+			name = strings.ToLower(name)
+		}
+		if matched, _ := path.Match(s.name, name); !matched {
+			return result, false, nil
+		}
+	}
+
+	// Size filters and content only apply to regular files
+	if entry.IsDir() {
+		if s.content != nil || s.options.MinSize > 0 || s.options.MaxSize > 0 {
+			return result, false, nil
+		}
+	} else if !entry.Type().IsRegular() {
+		return result, false, nil
+	}
+
+	info, err := entry.Info()
+	if err != nil {
+		return result, false, nil
+	}
+	result.ModTime = info.ModTime()
+	if !entry.IsDir() {
+		result.Size = info.Size()
+	}
+
+	switch {
+	case s.options.MinSize > 0 && result.Size < s.options.MinSize,
+		s.options.MaxSize > 0 && result.Size > s.options.MaxSize,
+		!s.options.ModifiedAfter.IsZero() && !result.ModTime.After(s.options.ModifiedAfter),
+		!s.options.ModifiedBefore.IsZero() && !result.ModTime.Before(s.options.ModifiedBefore):
+		return result, false, nil
+	}
+
+	if s.content == nil {
+		return result, true, nil
+	}
+	if s.options.MaxFileSize > 0 && result.Size > s.options.MaxFileSize {
+		return result, false, nil
+	}
+
+	limit := 0
+	if s.maxResults > 0 {
+		limit = s.maxResults - summary.Matches
+	}
+	result.Matches, err = s.grep(ctx, filePath, limit)
+	if err != nil {
+		return result, false, err
+	}
+	return result, len(result.Matches) > 0, nil
+}
+
+// grep returns up to limit matching lines of a file, nothing for binary or unreadable files
+func (s *searcher) grep(ctx context.Context, filePath string, limit int) ([]LineMatch, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, SEARCH_SNIFF_SIZE)
+	head, err := reader.Peek(SEARCH_SNIFF_SIZE)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, nil
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), SEARCH_MAX_LINE)
+
+	var matches []LineMatch
+	for line := 1; scanner.Scan(); line++ {
+		if line%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		text := scanner.Bytes()
+		if !s.content.Match(text) {
+			continue
+		}
+
+		if len(text) > SEARCH_LINE_LENGTH {
+			text = text[:SEARCH_LINE_LENGTH]
+		}
+		matches = append(matches, LineMatch{Line: line, Text: strings.ToValidUTF8(string(text), "")})
+		if limit > 0 && len(matches) >= limit {
+			break
+		}
+	}
+	// Lines over SEARCH_MAX_LINE end the scan, the matches found before are kept
+	return matches, nil
+}
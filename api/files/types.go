@@ -13,3 +13,71 @@ type FileInfo struct {
 	IsWritable   bool      `json:"isWritable"`
 	IsExecutable bool      `json:"isExecutable"`
 }
+
+type ArchiveFormat string
+
+const (
+	ArchiveFormatZip    ArchiveFormat = "zip"
+	ArchiveFormatTar    ArchiveFormat = "tar"
+	ArchiveFormatTarGz  ArchiveFormat = "tar.gz"
+	ArchiveFormatTarZst ArchiveFormat = "tar.zst"
+)
+
+// ArchiveLimits protects extraction against archive bombs, zero disables a limit
+type ArchiveLimits struct {
+	MaxSize    int64 // Total uncompressed bytes
+	MaxEntries int
+}
+
+// ArchiveProgress is reported after each archive entry
+type ArchiveProgress struct {
+	Entry   string `json:"entry"`
+	Entries int    `json:"entries"`           // Entries processed so far
+	Bytes   int64  `json:"bytes"`             // Uncompressed bytes processed so far
+	Skipped bool   `json:"skipped,omitempty"` // Links and special files are not extracted
+}
+
+// ArchiveSummary describes what a download archive would contain
+type ArchiveSummary struct {
+	Files       int   `json:"files"`
+	Directories int   `json:"directories"`
+	Size        int64 `json:"size"` // Total uncompressed bytes of the files
+}
+
+// SearchOptions filters a recursive search, zero values disable a filter
+type SearchOptions struct {
+	Paths          []string // Directories searched, relative to the base directory
+	Name           string   // Glob matched against base names
+	MinSize        int64
+	MaxSize        int64
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	Content        string // Text the lines of regular files are searched for, binary files are skipped
+	Regex          bool   // Content is a regular expression
+	IgnoreCase     bool   // Applies to both Name and Content
+	MaxResults     int    // Matching files, or matching lines when searching content
+	MaxFileSize    int64  // Larger files are not searched for content
+}
+
+// SearchResult is a matching file or directory, with its matching lines when searching content
+type SearchResult struct {
+	Path    string      `json:"path"`
+	Name    string      `json:"name"`
+	Size    int64       `json:"size"`
+	IsDir   bool        `json:"isDir"`
+	ModTime time.Time   `json:"modTime"`
+	Matches []LineMatch `json:"matches,omitempty"`
+}
+
+type LineMatch struct {
+	Line int    `json:"line"` // 1-based line number
+	Text string `json:"text"` // The line, cut to SEARCH_LINE_LENGTH bytes
+}
+
+// SearchSummary is the outcome of a search
+type SearchSummary struct {
+	Files     int  `json:"files"`     // Matching files and directories
+	Matches   int  `json:"matches"`   // Matching lines
+	Truncated bool `json:"truncated"` // MaxResults was reached
+	TimedOut  bool `json:"timedOut"`  // The context deadline passed before the search finished
+}
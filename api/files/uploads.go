@@ -0,0 +1,182 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	ErrOffsetMismatch = errors.New("upload offset does not match")
+	ErrUploadTooLarge = errors.New("upload exceeds its declared size")
+)
+
+// UploadStore stages the data of resumable uploads, one file per upload ID
+type UploadStore struct {
+	dir   string
+	locks sync.Map // upload ID -> *sync.Mutex
+}
+
+func NewUploadStore(dir string) *UploadStore {
+	return &UploadStore{dir: dir}
+}
+
+// Create creates the empty staging file for an upload
+func (s *UploadStore) Create(id string) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create upload directory: %v", err)
+	}
+
+	file, err := os.OpenFile(s.path(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create upload: %v", err)
+	}
+	return file.Close()
+}
+
+// Offset returns the number of bytes received so far
+func (s *UploadStore) Offset(id string) (int64, error) {
+	info, err := os.Stat(s.path(id))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read upload: %v", err)
+	}
+	return info.Size(), nil
+}
+
+// Append writes a chunk at offset, which has to be the current end of the upload.
+// Bytes received before a failure are kept so the client can resume from the returned offset.
+func (s *UploadStore) Append(id string, offset, size int64, chunk io.Reader) (int64, error) {
+	unlock := s.lock(id)
+	defer unlock()
+
+	file, err := os.OpenFile(s.path(id), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open upload: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read upload: %v", err)
+	}
+	current := info.Size()
+	if offset != current {
+		return current, ErrOffsetMismatch
+	}
+
+	// Read one byte past the declared size to detect clients sending too much
+	written, err := io.Copy(file, io.LimitReader(chunk, size-current+1))
+	current += written
+	if current > size {
+		if err := file.Truncate(size); err != nil {
+			return current, fmt.Errorf("failed to truncate upload: %v", err)
+		}
+		return size, ErrUploadTooLarge
+	}
+	if err != nil {
+		return current, fmt.Errorf("failed to write chunk: %v", err)
+	}
+	return current, nil
+}
+
+// Complete moves the finished upload to fullPath. The file is renamed into place, so a partially
+// copied file is never visible even when the staging directory is on another filesystem.
+func (s *UploadStore) Complete(id string, fullPath string) error {
+	unlock := s.lock(id)
+	defer unlock()
+
+	if err := moveFile(s.path(id), fullPath); err != nil {
+		return err
+	}
+	s.locks.Delete(id)
+	return nil
+}
+
+// Remove deletes the staged data of an upload
+func (s *UploadStore) Remove(id string) error {
+	unlock := s.lock(id)
+	defer unlock()
+
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove upload: %v", err)
+	}
+	s.locks.Delete(id)
+	return nil
+}
+
+// Stale returns the IDs of uploads whose data was last written before the given time
+func (s *UploadStore) Stale(before time.Time) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read upload directory: %v", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if info.ModTime().Before(before) {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids, nil
+}
+
+func (s *UploadStore) path(id string) string {
+	return filepath.Join(s.dir, filepath.Base(id))
+}
+
+func (s *UploadStore) lock(id string) func() {
+	mu, _ := s.locks.LoadOrStore(id, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// moveFile renames source to destination, copying through a temporary file next to destination across filesystems
+func moveFile(source, destination string) error {
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	if err := os.Rename(source, destination); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open upload: %v", err)
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(destination), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save file: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to save file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), destination); err != nil {
+		return fmt.Errorf("failed to save file: %v", err)
+	}
+
+	os.Remove(source)
+	return nil
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gsm/config"
+	"gsm/files"
+	"log"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+)
+
+type extractRequest struct {
+	Source      string `json:"source" binding:"required"`      // Archive to extract
+	Destination string `json:"destination" binding:"required"` // Directory the entries are extracted into
+}
+
+type compressRequest struct {
+	Paths       []string            `json:"paths" binding:"required,min=1"`
+	Destination string              `json:"destination" binding:"required"` // Archive file to create
+	Format      files.ArchiveFormat `json:"format"`                         // Defaults to the format of the destination's extension
+}
+
+// extractArchive extracts a zip, tar, tar.gz or tar.zst archive, streaming progress as server-sent events
+func (h *FileHandler) extractArchive() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req extractRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if !h.authorize(c, req.Source, req.Destination) {
+			return
+		}
+
+		cfg := config.Get()
+		limits := files.ArchiveLimits{MaxSize: cfg.ExtractMaxSize, MaxEntries: cfg.ExtractMaxEntries}
+
+		streamArchiveProgress(c, func(progress func(files.ArchiveProgress)) error {
+			return h.cli.Extract(req.Source, req.Destination, limits, progress)
+		})
+	}
+}
+
+// compressPaths creates an archive of files and directories, streaming progress as server-sent events
+func (h *FileHandler) compressPaths() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req compressRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if req.Format == "" {
+			format, ok := files.ArchiveFormatFromName(req.Destination)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "format is required when the destination has no archive extension"})
+				return
+			}
+			req.Format = format
+		}
+		if !files.IsValidArchiveFormat(req.Format) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported format %s, expected zip, tar, tar.gz or tar.zst", req.Format)})
+			return
+		}
+
+		if !h.authorize(c, append(req.Paths, req.Destination)...) {
+			return
+		}
+
+		streamArchiveProgress(c, func(progress func(files.ArchiveProgress)) error {
+			return h.cli.Compress(req.Paths, req.Destination, req.Format, progress)
+		})
+	}
+}
+
+// downloadArchive streams an archive of several files and directories, leaving out paths matching the exclude
+// patterns. With dryRun=true it only reports the number of files and their uncompressed size.
+func (h *FileHandler) downloadArchive() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		paths := c.QueryArray("path")
+		excludes := c.QueryArray("exclude")
+		if len(paths) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+			return
+		}
+
+		format := files.ArchiveFormat(c.DefaultQuery("format", string(files.ArchiveFormatZip)))
+		if !files.IsValidArchiveFormat(format) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported format %s, expected zip, tar, tar.gz or tar.zst", format)})
+			return
+		}
+
+		if !h.authorize(c, paths...) {
+			return
+		}
+
+		if c.Query("dryRun") == "true" {
+			summary, err := h.cli.SummarizeArchive(paths, excludes)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, summary)
+			return
+		}
+
+		name := "download"
+		if len(paths) == 1 && volumeContainer(paths[0]) != "" {
+			name = path.Base(path.Clean("/" + paths[0]))
+		}
+		c.Header("Content-Disposition", contentDisposition(name+"."+string(format)))
+		c.Header("Content-Type", archiveContentType(format))
+
+		if err := h.cli.DownloadArchive(paths, excludes, format, c.Writer); err != nil {
+			if !c.Writer.Written() {
+				c.Header("Content-Disposition", "")
+				c.Header("Content-Type", "")
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Failed to stream archive of %v: %v", paths, err)
+		}
+	}
+}
+
+func archiveContentType(format files.ArchiveFormat) string {
+	switch format {
+	case files.ArchiveFormatTar:
+		return "application/x-tar"
+	case files.ArchiveFormatTarGz:
+		return "application/gzip"
+	case files.ArchiveFormatTarZst:
+		return "application/zstd"
+	}
+	return "application/zip"
+}
+
+// streamArchiveProgress runs an archive operation, sending an event per entry and a final event
+// with either "done" and the totals or "error"
+func streamArchiveProgress(c *gin.Context, run func(progress func(files.ArchiveProgress)) error) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Writer.WriteHeader(http.StatusOK)
+
+	send := func(data interface{}) {
+		if eventJSON, err := json.Marshal(data); err == nil {
+			c.Writer.Write([]byte("data: " + string(eventJSON) + "\n\n"))
+			c.Writer.Flush()
+		}
+	}
+
+	var last files.ArchiveProgress
+	err := run(func(progress files.ArchiveProgress) {
+		last = progress
+		send(progress)
+	})
+	if err != nil {
+		send(gin.H{"error": err.Error()})
+		return
+	}
+
+	send(gin.H{"done": true, "entries": last.Entries, "bytes": last.Bytes})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	middleware "gsm/middleware"
+	"gsm/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	AUDIT_DEFAULT_PAGE_SIZE = 50
+	AUDIT_MAX_PAGE_SIZE     = 200
+)
+
+type auditHandler struct {
+	db *gorm.DB
+}
+
+func NewAuditHandler(db *gorm.DB) *auditHandler {
+	return &auditHandler{db: db}
+}
+
+// RegisterAuditRoutes registers all audit-related handlers with the given router group
+func (h *auditHandler) RegisterAuditRoutes(rg *gin.RouterGroup) {
+	rg.Use(middleware.CheckUser(h.db), middleware.RequireUser, middleware.RequirePermission(models.PermissionAuditView))
+
+	rg.GET("/", h.listAuditEntries)
+}
+
+// listAuditEntries returns a page of audit entries, newest first.
+// Supported filters: actor, action (substring), target (substring), outcome, from and to (RFC3339).
+func (h *auditHandler) listAuditEntries(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page"})
+		return
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(AUDIT_DEFAULT_PAGE_SIZE)))
+	if err != nil || pageSize < 1 || pageSize > AUDIT_MAX_PAGE_SIZE {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page size"})
+		return
+	}
+
+	query := h.db.Model(&models.AuditEntry{})
+	if actor := c.Query("actor"); actor != "" {
+		query = query.Where("actor = ?", actor)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action LIKE ?", "%"+action+"%")
+	}
+	if target := c.Query("target"); target != "" {
+		query = query.Where("target LIKE ?", "%"+target+"%")
+	}
+	if outcome := c.Query("outcome"); outcome != "" {
+		query = query.Where("outcome = ?", outcome)
+	}
+	for param, op := range map[string]string{"from": ">=", "to": "<="} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " time, expected RFC3339"})
+			return
+		}
+		query = query.Where("created_at "+op+" ?", t)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count audit entries"})
+		return
+	}
+
+	var entries []models.AuditEntry
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit entries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":    entries,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
@@ -3,44 +3,61 @@ package handlers
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
+	"gsm/auth"
 	"gsm/config"
 	middleware "gsm/middleware"
 	"gsm/models"
+	"log"
 	"net/http"
 	"strings"
-	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
-
-	"google.golang.org/api/idtoken"
 	"gorm.io/gorm"
 )
 
 type authHandler struct {
-	db           *gorm.DB
-	oauth2Config oauth2.Config
-	secure       bool
-	sameSite     http.SameSite
+	db        *gorm.DB
+	providers []auth.Provider
+	secure    bool
+	sameSite  http.SameSite
 }
 
-func NewAuthHandler(db *gorm.DB) *authHandler {
+func NewAuthHandler(db *gorm.DB) (*authHandler, error) {
 	cfg := config.Get()
 
+	providers, err := auth.Load(db)
+	if err != nil {
+		return nil, err
+	}
+
 	secure := strings.ToLower(cfg.AppEnv) == "production"
 	sameSite := getSameSite()
 
-	return &authHandler{db: db, oauth2Config: initOAuthConfig(), secure: secure, sameSite: sameSite}
+	return &authHandler{db: db, providers: providers, secure: secure, sameSite: sameSite}, nil
 }
 
 // RegisterAuthHandlers registers all auth-related handlers with the given router groups
 func (h *authHandler) RegisterAuthHandlers(rg *gin.RouterGroup) {
-	rg.GET("/", middleware.CheckUser, h.getUser)
-	rg.GET("/signin", h.googleSignIn)
+	rg.GET("/", middleware.CheckUser(h.db), h.getUser)
+	rg.GET("/providers", h.listProviders)
 	rg.GET("/signout", h.signOut)
-	rg.GET("/callback", h.googleOAuthCallback)
+	rg.POST("/refresh", h.refresh)
+	rg.GET("/:provider/signin", h.oauthSignIn)
+	rg.GET("/:provider/callback", h.oauthCallback)
+	rg.POST("/:provider/signin", h.passwordSignIn)
+
+	// Routes from before providers were configurable, existing Google redirect URLs point here
+	rg.GET("/signin", withProvider("google", h.oauthSignIn))
+	rg.GET("/callback", withProvider("google", h.oauthCallback))
+}
+
+// withProvider serves a route without a provider parameter as if it had one
+func withProvider(name string, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Params = append(c.Params, gin.Param{Key: "provider", Value: name})
+		handler(c)
+	}
 }
 
 func generateState() string {
@@ -52,37 +69,59 @@ func generateState() string {
 	return base64.URLEncoding.EncodeToString(b)
 }
 
-func initOAuthConfig() oauth2.Config {
-	cfg := config.Get()
+func (h *authHandler) provider(name string) auth.Provider {
+	for _, provider := range h.providers {
+		if provider.Name() == name {
+			return provider
+		}
+	}
+	return nil
+}
 
-	return oauth2.Config{
-		ClientID:     cfg.GoogleClientID,
-		ClientSecret: cfg.GoogleSecret,
-		RedirectURL:  cfg.GoogleRedirect, // Make sure this matches your redirect URI
-		Scopes: []string{
-			"https://www.googleapis.com/auth/userinfo.email",
-			"https://www.googleapis.com/auth/userinfo.profile",
-		},
-		Endpoint: google.Endpoint,
+func (h *authHandler) listProviders(c *gin.Context) {
+	providers := []gin.H{}
+	for _, provider := range h.providers {
+		providers = append(providers, gin.H{
+			"name":        provider.Name(),
+			"displayName": provider.DisplayName(),
+			"type":        provider.Type(),
+		})
 	}
+	c.JSON(http.StatusOK, providers)
 }
 
-func (h *authHandler) googleSignIn(c *gin.Context) {
+func (h *authHandler) oauthSignIn(c *gin.Context) {
 	cfg := config.Get()
-	oauth2Config := initOAuthConfig()
+
+	provider, ok := h.provider(c.Param("provider")).(auth.OAuthProvider)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Auth provider not found"})
+		return
+	}
 
 	oauth2State := generateState()
+	authURL, err := provider.AuthCodeURL(oauth2State)
+	if err != nil {
+		log.Printf("Failed to start %s sign in: %v", provider.Name(), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Auth provider unavailable"})
+		return
+	}
+
 	c.SetSameSite(h.sameSite)
 	c.SetCookie("csrf", oauth2State, 3600, "/", cfg.CookieDomain, h.secure, true) // Set cookie for 1 hour
 
-	// Redirect the user to Google's OAuth consent screen
-	authURL := oauth2Config.AuthCodeURL(oauth2State, oauth2.AccessTypeOffline)
+	// Redirect the user to the provider's consent screen
 	c.Redirect(http.StatusFound, authURL)
 }
 
-func (h *authHandler) googleOAuthCallback(c *gin.Context) {
+func (h *authHandler) oauthCallback(c *gin.Context) {
 	cfg := config.Get()
-	oauth2Config := initOAuthConfig()
+
+	provider, ok := h.provider(c.Param("provider")).(auth.OAuthProvider)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Auth provider not found"})
+		return
+	}
 
 	// Validate the state parameter to protect against CSRF attacks
 	storedState, err := c.Cookie("csrf")
@@ -96,48 +135,83 @@ func (h *authHandler) googleOAuthCallback(c *gin.Context) {
 		return
 	}
 
-	// Exchange the authorization code for an access token
 	code := c.DefaultQuery("code", "")
 	if code == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Authorization code missing"})
 		return
 	}
 
-	// Exchange the code for a token
-	token, err := oauth2Config.Exchange(c, code)
+	// Exchange the code for the user's identity
+	identity, err := provider.Exchange(c, code)
+	if errors.Is(err, auth.ErrNotAllowed) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not allowed to sign in"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to exchange code for token"})
+		log.Printf("Failed %s sign in: %v", provider.Name(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Could not verify identity"})
 		return
 	}
 
-	// Validate the ID token
-	payload, err := idtoken.Validate(c, token.Extra("id_token").(string), cfg.GoogleClientID)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token"})
+	if !h.signIn(c, provider.Name(), identity) {
 		return
 	}
 
-	// Extract the email and picture from the ID token
-	email, ok := payload.Claims["email"].(string)
+	c.Redirect(http.StatusFound, cfg.AllowOrigin)
+}
+
+func (h *authHandler) passwordSignIn(c *gin.Context) {
+	provider, ok := h.provider(c.Param("provider")).(auth.PasswordProvider)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Email not found in token"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Auth provider not found"})
+		return
+	}
+
+	var req struct {
+		Email    string `json:"email" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
-	picture, _ := payload.Claims["picture"].(string)
+	identity, err := provider.Authenticate(c, req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not verify credentials"})
+		return
+	}
 
+	if !h.signIn(c, provider.Name(), identity) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Signed in"})
+}
+
+// signIn maps the identity onto its allowed user and starts a session, it responds with an error and returns false on failure
+func (h *authHandler) signIn(c *gin.Context, provider string, identity *auth.Identity) bool {
 	// Check if the user is allowed to sign in
 	var allowedUser models.AllowedUser
-	if err := h.db.Where("email = ?", email).First(&allowedUser).Error; err != nil {
+	if identity.Role != "" {
+		if err := h.provisionAllowedUser(provider, identity, &allowedUser); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not provision user"})
+			return false
+		}
+	} else if err := h.db.Where("email = ?", identity.Email).First(&allowedUser).Error; err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not allowed to sign in"})
-		return
+		return false
 	}
 
 	// Create a new user instance or update existing one
 	user := models.User{
-		Email:   email,
+		Email:   identity.Email,
 		Role:    allowedUser.Role,
-		Picture: picture,
+		Picture: identity.Picture,
 	}
 
 	var existingUser models.User
@@ -145,37 +219,30 @@ func (h *authHandler) googleOAuthCallback(c *gin.Context) {
 		if err == gorm.ErrRecordNotFound {
 			if err := h.db.Create(&user).Error; err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create user"})
-				return
+				return false
 			}
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-			return
+			return false
 		}
 	} else {
+		// Password sign ins carry no picture, keep the one from an earlier sign in
+		if user.Picture == "" {
+			user.Picture = existingUser.Picture
+		}
 		if err := h.db.Model(&existingUser).Updates(user).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update user"})
-			return
+			return false
 		}
+		user.ID = existingUser.ID
 	}
 
-	// Generate JWT token for the user
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":      user.ID,
-		"email":   user.Email,
-		"role":    user.Role,
-		"picture": user.Picture,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
-	})
-	tokenString, err := jwtToken.SignedString([]byte(cfg.JWTSecret))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
-		return
+	if err := h.startSession(c, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start session"})
+		return false
 	}
 
-	c.SetSameSite(h.sameSite)
-	c.SetCookie("token", tokenString, 86400, "/", cfg.CookieDomain, h.secure, true) // Set cookie for 1 day
-
-	c.Redirect(http.StatusFound, cfg.AllowOrigin)
+	return true
 }
 
 func (h *authHandler) getUser(c *gin.Context) {
@@ -204,8 +271,11 @@ func (h *authHandler) getUser(c *gin.Context) {
 		return
 	}
 
-	// Return the user information as JSON
-	c.JSON(http.StatusOK, user)
+	// Return the user information along with what the role allows, so clients can hide unavailable actions
+	c.JSON(http.StatusOK, struct {
+		models.User
+		Permissions []models.Permission `json:"permissions"`
+	}{user, user.Role.Permissions()})
 }
 
 func getSameSite() http.SameSite {
@@ -221,8 +291,40 @@ func getSameSite() http.SameSite {
 
 func (h *authHandler) signOut(c *gin.Context) {
 	cfg := config.Get()
-	c.SetSameSite(h.sameSite)
-	c.SetCookie("token", "", -1, "/", cfg.CookieDomain, h.secure, true) // Set cookie with an expired date
+
+	// Revoke the session so its access token stops working before it expires
+	if refreshToken, err := c.Cookie("refresh_token"); err == nil {
+		var session models.Session
+		if err := h.db.Where("refresh_hash = ?", models.HashToken(refreshToken)).First(&session).Error; err == nil {
+			revokeSession(h.db, &session, "signed out")
+		}
+	}
+
+	h.clearSessionCookies(c)
 
 	c.Redirect(http.StatusFound, cfg.AllowOrigin)
 }
+
+// provisionAllowedUser creates or updates the allowed user for an identity whose role was decided by the provider.
+// Only users the provider created are updated, users added by hand and the admin from ADMIN_EMAIL keep their role.
+// Sessions started with a previous role are revoked, like when an admin changes the role.
+func (h *authHandler) provisionAllowedUser(provider string, identity *auth.Identity, allowedUser *models.AllowedUser) error {
+	err := h.db.Where("email = ?", identity.Email).First(allowedUser).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		*allowedUser = models.AllowedUser{Email: identity.Email, Role: identity.Role, ManagedBy: provider}
+		return h.db.Create(allowedUser).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	if allowedUser.ManagedBy != provider || allowedUser.Email == config.Get().AdminEmail || allowedUser.Role == identity.Role {
+		return nil
+	}
+
+	allowedUser.Role = identity.Role
+	if err := h.db.Save(allowedUser).Error; err != nil {
+		return err
+	}
+	return revokeSessions(h.db, identity.Email, "role changed")
+}
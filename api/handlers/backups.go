@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"gsm/backup"
+	"gsm/config"
+	"gsm/docker"
+	middleware "gsm/middleware"
+	"gsm/models"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type backupsHandler struct {
+	db      *gorm.DB
+	cli     docker.Client
+	backups backup.Client
+}
+
+func NewBackupsHandler(db *gorm.DB) (*backupsHandler, error) {
+	cfg := config.Get()
+
+	cli, err := NewDockerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	volumesDir := path.Join(cfg.DataDir, cfg.VolumeDir)
+	backupsDir := path.Join(cfg.DataDir, cfg.BackupDir)
+
+	return &backupsHandler{db: db, cli: cli, backups: backup.NewClient(volumesDir, backupsDir)}, nil
+}
+
+// RegisterBackupsRoutes registers all backup-related handlers with the given router group
+func (h *backupsHandler) RegisterBackupsRoutes(rg *gin.RouterGroup) {
+	rg.Use(middleware.CheckUser(h.db), middleware.RequireUser, middleware.RequirePermission(models.PermissionBackupsManage))
+
+	rg.GET("/:id", h.listSnapshots)
+	rg.POST("/:id", h.createSnapshot)
+	rg.POST("/:id/:snapshot/restore", h.restoreSnapshot)
+	rg.DELETE("/:id/:snapshot", h.deleteSnapshot)
+	rg.GET("/:id/policy", h.getPolicy)
+	rg.PUT("/:id/policy", h.updatePolicy)
+}
+
+func (h *backupsHandler) listSnapshots(c *gin.Context) {
+	inspect, ok := h.inspect(c)
+	if !ok {
+		return
+	}
+
+	snapshots, err := h.backups.ListSnapshots(containerName(inspect))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshots)
+}
+
+func (h *backupsHandler) createSnapshot(c *gin.Context) {
+	inspect, ok := h.inspect(c)
+	if !ok {
+		return
+	}
+
+	name := containerName(inspect)
+	snapshot, err := h.backups.CreateSnapshot(name, h.retain(name))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, snapshot)
+}
+
+func (h *backupsHandler) restoreSnapshot(c *gin.Context) {
+	inspect, ok := h.inspect(c)
+	if !ok {
+		return
+	}
+
+	// Check the snapshot before stopping, a bad name must not restart a live server
+	name := containerName(inspect)
+	if _, err := h.backups.Snapshot(name, c.Param("snapshot")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Stop the server so it does not write to the volume while it is being replaced
+	if inspect.State.Running {
+		if err := h.cli.StopContainer(c, inspect.ID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to stop container: %v", err)})
+			return
+		}
+	}
+
+	restoreErr := h.backups.RestoreSnapshot(name, c.Param("snapshot"))
+
+	// Bring the server back even if the restore failed, the old volume is left in place then
+	if inspect.State.Running {
+		if err := h.cli.StartContainer(context.WithoutCancel(c), inspect.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to start container: %v", err)})
+			return
+		}
+	}
+
+	if restoreErr != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": restoreErr.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "snapshot restored successfully"})
+}
+
+func (h *backupsHandler) deleteSnapshot(c *gin.Context) {
+	inspect, ok := h.inspect(c)
+	if !ok {
+		return
+	}
+
+	if err := h.backups.DeleteSnapshot(containerName(inspect), c.Param("snapshot")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *backupsHandler) getPolicy(c *gin.Context) {
+	inspect, ok := h.inspect(c)
+	if !ok {
+		return
+	}
+
+	name := containerName(inspect)
+	c.JSON(http.StatusOK, models.BackupPolicy{Container: name, Retain: h.retain(name)})
+}
+
+func (h *backupsHandler) updatePolicy(c *gin.Context) {
+	inspect, ok := h.inspect(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Retain int `json:"retain" binding:"gte=0"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	policy := models.BackupPolicy{Container: containerName(inspect)}
+	if err := h.db.Where(&policy).FirstOrInit(&policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	policy.Retain = req.Retain
+	if err := h.db.Save(&policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save backup policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *backupsHandler) inspect(c *gin.Context) (*docker.ContainerInspect, bool) {
+	inspect, err := h.cli.InspectContainer(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+		return nil, false
+	}
+	return inspect, true
+}
+
+// retain returns the number of snapshots to keep for the container
+func (h *backupsHandler) retain(container string) int {
+	var policy models.BackupPolicy
+	if err := h.db.Where("container = ?", container).First(&policy).Error; err != nil {
+		return config.Get().BackupRetain
+	}
+	return policy.Retain
+}
+
+// containerName returns the name used for the container's volume directory
+func containerName(inspect *docker.ContainerInspect) string {
+	return strings.TrimPrefix(inspect.Name, "/")
+}
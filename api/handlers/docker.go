@@ -2,24 +2,67 @@ package handlers
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
+	"gsm/acl"
 	"gsm/config"
 	"gsm/docker"
 	middleware "gsm/middleware"
+	"gsm/models"
+	"gsm/registry"
+	"gsm/updates"
 	"io"
 	"net/http"
 	"path"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/docker/docker/api/types/events"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type DockerHandler struct {
-	cli docker.Client
+	db           *gorm.DB
+	cli          docker.Client
+	acl          *acl.ACL
+	registry     registry.Client
+	credentials  *registry.Credentials
+	updates      *updates.Checker
+	execSessions sync.Map // exec ID -> execSession, sessions created but not yet attached
 }
 
-func NewDockerHandler() (*DockerHandler, error) {
+const (
+	EXEC_SESSION_TTL      = time.Minute            // Sessions not attached within this time are discarded
+	INSPECT_QUERY_TIMEOUT = 750 * time.Millisecond // Game server query budget within inspect, well under query.DEFAULT_TIMEOUT
+)
+
+type execSession struct {
+	tty       bool
+	owner     string // Email of the user who created the session, the only one allowed to attach
+	createdAt time.Time
+}
+
+func NewDockerHandler(db *gorm.DB, checker *updates.Checker) (*DockerHandler, error) {
+	cli, err := NewDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	credentials := registry.NewCredentials(db)
+	return &DockerHandler{
+		db:          db,
+		cli:         cli,
+		acl:         acl.New(db),
+		registry:    registry.NewClient(config.Get().RegistryURL, credentials),
+		credentials: credentials,
+		updates:     checker,
+	}, nil
+}
+
+// NewDockerClient creates a docker client whose volumes live under the host data directory
+func NewDockerClient() (docker.Client, error) {
 	cfg := config.Get()
 	volumesDir := path.Join(cfg.HostHome, cfg.DataDir, cfg.VolumeDir)
 
@@ -27,33 +70,67 @@ func NewDockerHandler() (*DockerHandler, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %v", err)
 	}
-	return &DockerHandler{cli: cli}, nil
+	return cli, nil
 }
 
 // RegisterDockerHandlers registers all docker-related handlers with the given router groups
 func (h *DockerHandler) RegisterDockerHandlers(rg *gin.RouterGroup) {
-	rg.Use(middleware.CheckUser, middleware.RequireUser)
+	rg.Use(middleware.CheckUser(h.db), middleware.RequireUser)
+
+	view := h.requireContainerAction(models.ContainerActionView)
+	control := h.requireContainerAction(models.ContainerActionControl)
+	console := h.requireContainerAction(models.ContainerActionConsole)
+	edit := h.requireContainerAction(models.ContainerActionEdit)
 
 	// Container endpoints
-	rg.GET("/containers", h.listContainers())
-	rg.GET("/containers/:id", h.inspectContainer())
-	rg.POST("/containers", middleware.RequireRole("admin"), h.createContainer())
-	rg.DELETE("/containers/:id", middleware.RequireRole("admin"), h.removeContainer())
-	rg.POST("/containers/:id/start", h.startContainer())
-	rg.POST("/containers/:id/stop", h.stopContainer())
-	rg.POST("/containers/:id/restart", h.restartContainer())
-	rg.GET("/containers/:id/logs", h.getLogs())
-	rg.GET("/containers/:id/logs-stream", h.streamLogs())
-	rg.POST("/containers/:id/exec", middleware.RequireRole("admin"), h.execInContainer())
-	rg.PUT("/containers/:id", middleware.RequireRole("admin"), h.updateContainer())
+	rg.GET("/containers", middleware.RequireScope(models.PermissionContainersView), h.listContainers())
+	rg.GET("/containers/:id", view, h.inspectContainer())
+	rg.POST("/containers", middleware.RequirePermission(models.PermissionContainersCreate), h.createContainer())
+	rg.DELETE("/containers/:id", middleware.RequirePermission(models.PermissionContainersDelete), h.removeContainer())
+	rg.POST("/containers/:id/start", control, h.startContainer())
+	rg.POST("/containers/:id/stop", control, h.stopContainer())
+	rg.POST("/containers/:id/restart", control, h.restartContainer())
+	rg.GET("/containers/:id/logs", view, h.getLogs())
+	rg.GET("/containers/:id/logs-stream", view, h.streamLogs())
+	rg.GET("/containers/:id/stats", view, h.getStats())
+	rg.GET("/containers/:id/stats-stream", view, h.streamStats())
+	rg.POST("/containers/:id/exec", console, h.execInContainer())
+	rg.PUT("/containers/:id", edit, h.updateContainer())
+	rg.GET("/containers/:id/update", view, h.getImageUpdate())
+	rg.POST("/containers/:id/upgrade", edit, h.upgradeContainer())
+	rg.POST("/containers/:id/exec-sessions", console, h.createExecSession())
+	rg.GET("/exec-sessions/:execId", h.inspectExecSession())
+	rg.GET("/exec-sessions/:execId/ws", h.streamExecSession())
+	rg.GET("/containers/:id/attach", middleware.RequirePermission(models.PermissionContainersAttach), h.attachContainer())
+	rg.GET("/containers/:id/query", edit, h.getQuerySettings())
+	rg.PUT("/containers/:id/query", edit, h.updateQuerySettings())
+	rg.GET("/containers/:id/rcon", edit, h.getRconSettings())
+	rg.PUT("/containers/:id/rcon", edit, h.updateRconSettings())
+	rg.POST("/containers/:id/rcon", console, h.execRcon())
+
+	// Permission endpoints
+	rg.GET("/containers/:id/permissions/me", view, h.getMyPermissions())
+	managePermissions := middleware.RequirePermission(models.PermissionPermissionsManage)
+	rg.GET("/containers/:id/permissions", managePermissions, h.listPermissions())
+	rg.POST("/containers/:id/permissions", managePermissions, h.grantPermissions())
+	rg.DELETE("/containers/:id/permissions/:email", managePermissions, h.revokePermissions())
 
 	// Image endpoints
-	rg.GET("/images", middleware.RequireRole("admin"), h.listImages())
-	rg.DELETE("/images/:id", middleware.RequireRole("admin"), h.removeImage())
-	rg.GET("/images/pull", middleware.RequireRole("admin"), h.pullImage())
+	rg.GET("/images", middleware.RequirePermission(models.PermissionImagesView), h.listImages())
+	rg.DELETE("/images/:id", middleware.RequirePermission(models.PermissionImagesDelete), h.removeImage())
+	rg.GET("/images/pull", middleware.RequirePermission(models.PermissionImagesPull), h.pullImage())
+	rg.GET("/images/search", middleware.RequirePermission(models.PermissionImagesView), h.searchImages())
+	rg.GET("/images/tags", middleware.RequirePermission(models.PermissionImagesView), h.listImageTags())
+
+	// Registry credential endpoints
+	manageRegistries := middleware.RequirePermission(models.PermissionRegistriesManage)
+	rg.GET("/registries", manageRegistries, h.listRegistries())
+	rg.POST("/registries", manageRegistries, h.createRegistry())
+	rg.PUT("/registries/:registryId", manageRegistries, h.updateRegistry())
+	rg.DELETE("/registries/:registryId", manageRegistries, h.removeRegistry())
 
 	// Events endpoint
-	rg.GET("/events-stream", h.streamDockerEvents())
+	rg.GET("/events-stream", middleware.RequireScope(models.PermissionContainersView), h.streamDockerEvents())
 }
 
 func (h *DockerHandler) listContainers() gin.HandlerFunc {
@@ -64,6 +141,32 @@ func (h *DockerHandler) listContainers() gin.HandlerFunc {
 			return
 		}
 
+		email, role := currentUser(c)
+		allowed, all, err := h.acl.Containers(email, role, models.ContainerActionView)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !all {
+			visible := []docker.ContainerListItem{}
+			for _, container := range containers {
+				for _, name := range container.Names {
+					if allowed[strings.TrimPrefix(name, "/")] {
+						visible = append(visible, container)
+						break
+					}
+				}
+			}
+			containers = visible
+		}
+
+		for i := range containers {
+			if status, ok := h.updates.Status(containers[i].ID); ok {
+				containers[i].UpdateAvailable = status.UpdateAvailable
+			}
+		}
+
 		c.JSON(200, containers)
 	}
 }
@@ -78,6 +181,11 @@ func (h *DockerHandler) inspectContainer() gin.HandlerFunc {
 			return
 		}
 
+		// The UI polls inspect, so a slow or unreachable server must not hold it up
+		ctx, cancel := context.WithTimeout(c, INSPECT_QUERY_TIMEOUT)
+		defer cancel()
+		inspect.Query = h.queryServer(ctx, inspect)
+
 		c.JSON(200, inspect)
 	}
 }
@@ -137,7 +245,13 @@ func (h *DockerHandler) pullImage() gin.HandlerFunc {
 			return
 		}
 
-		pullStream, err := h.cli.PullImage(c, imageName)
+		registryAuth, err := h.credentials.PullAuth(imageName)
+		if err != nil {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("failed to pull image: %v", err)})
+			return
+		}
+
+		pullStream, err := h.cli.PullImage(c, imageName, registryAuth)
 		if err != nil {
 			c.JSON(400, gin.H{"error": fmt.Sprintf("failed to pull image: %v", err)})
 			return
@@ -304,6 +418,62 @@ func (h *DockerHandler) getLogs() gin.HandlerFunc {
 	}
 }
 
+func (h *DockerHandler) getStats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		stats, err := h.cli.ContainerStats(c, id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to fetch stats: %v", err)})
+			return
+		}
+
+		c.JSON(http.StatusOK, stats)
+	}
+}
+
+func (h *DockerHandler) streamStats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Writer.WriteHeader(http.StatusOK)
+		c.Writer.Flush()
+
+		statsChan, errChan := h.cli.StreamContainerStats(c.Request.Context(), id)
+
+		heartbeat := time.NewTicker(30 * time.Second)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case stats, ok := <-statsChan:
+				if !ok {
+					select {
+					case err := <-errChan:
+						c.Writer.Write([]byte(fmt.Sprintf("data: {\"error\": %q}\n\n", err.Error())))
+					default:
+						c.Writer.Write([]byte("data: [EOF]\n\n"))
+					}
+					c.Writer.Flush()
+					return
+				}
+				if statsJSON, err := json.Marshal(stats); err == nil {
+					c.Writer.Write([]byte("data: " + string(statsJSON) + "\n\n"))
+					c.Writer.Flush()
+				}
+			case <-heartbeat.C:
+				c.Writer.Write([]byte(": heartbeat\n\n"))
+				c.Writer.Flush()
+			case <-c.Request.Context().Done():
+				return
+			}
+		}
+	}
+}
+
 func (h *DockerHandler) streamDockerEvents() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Content-Type", "text/event-stream")
@@ -312,8 +482,20 @@ func (h *DockerHandler) streamDockerEvents() gin.HandlerFunc {
 		c.Writer.WriteHeader(http.StatusOK)
 		c.Writer.Flush()
 
+		// Users without a global view only receive events of containers they can view
+		email, role := currentUser(c)
+		allowed, all, err := h.acl.Containers(email, role, models.ContainerActionView)
+		if err != nil {
+			c.Writer.Write([]byte(fmt.Sprintf("data: {\"error\": %q}\n\n", err.Error())))
+			c.Writer.Flush()
+			return
+		}
+
 		eventsChan, errChan := h.cli.StreamEvents(c)
 
+		// Last health status per container ID, only changes are sent
+		healthStatuses := make(map[string]string)
+
 		heartbeat := time.NewTicker(30 * time.Second)
 		defer heartbeat.Stop()
 
@@ -324,6 +506,9 @@ func (h *DockerHandler) streamDockerEvents() gin.HandlerFunc {
 					c.JSON(http.StatusInternalServerError, gin.H{"error": "docker events channel closed"})
 					return
 				}
+				if !all && (event.Type != events.ContainerEventType || !allowed[event.Actor.Attributes["name"]]) {
+					continue
+				}
 				eventData := gin.H{
 					"event_type": event.Type,
 					"action":     event.Action,
@@ -331,6 +516,20 @@ func (h *DockerHandler) streamDockerEvents() gin.HandlerFunc {
 					"attributes": event.Actor.Attributes,
 					"time":       event.Time,
 				}
+				// Health events have an action like "health_status: unhealthy"
+				if status, found := strings.CutPrefix(string(event.Action), string(events.ActionHealthStatus)+": "); found {
+					previous, seen := healthStatuses[event.Actor.ID]
+					if seen && previous == status {
+						continue
+					}
+					healthStatuses[event.Actor.ID] = status
+					eventData["action"] = events.ActionHealthStatus
+					eventData["health_status"] = status
+					eventData["previous_health_status"] = previous
+				}
+				if event.Action == events.ActionDestroy {
+					delete(healthStatuses, event.Actor.ID)
+				}
 				if eventJSON, err := json.Marshal(eventData); err == nil {
 					c.Writer.Write([]byte("data: " + string(eventJSON) + "\n\n"))
 					c.Writer.Flush()
@@ -371,6 +570,175 @@ func (h *DockerHandler) execInContainer() gin.HandlerFunc {
 	}
 }
 
+func (h *DockerHandler) createExecSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		containerID := c.Param("id")
+		var req struct {
+			Command []string `json:"command"`
+			Tty     *bool    `json:"tty"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+			c.JSON(400, gin.H{"error": "invalid request"})
+			return
+		}
+
+		// Default to an interactive shell with a TTY
+		if len(req.Command) == 0 {
+			req.Command = []string{"/bin/sh"}
+		}
+		tty := req.Tty == nil || *req.Tty
+
+		execID, err := h.cli.CreateExecSession(c, containerID, req.Command, tty)
+		if err != nil {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("failed to create exec session: %v", err)})
+			return
+		}
+
+		h.removeExpiredExecSessions()
+		h.execSessions.Store(execID, execSession{tty: tty, owner: c.GetString("userEmail"), createdAt: time.Now()})
+
+		c.JSON(http.StatusCreated, gin.H{
+			"id":  execID,
+			"tty": tty,
+		})
+	}
+}
+
+// removeExpiredExecSessions forgets sessions that were created but never attached within EXEC_SESSION_TTL.
+// Docker keeps their processes unstarted, so nothing has to be stopped.
+func (h *DockerHandler) removeExpiredExecSessions() {
+	h.execSessions.Range(func(key, value interface{}) bool {
+		if time.Since(value.(execSession).createdAt) > EXEC_SESSION_TTL {
+			h.execSessions.Delete(key)
+		}
+		return true
+	})
+}
+
+func (h *DockerHandler) inspectExecSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inspect, err := h.cli.InspectExecSession(c, c.Param("execId"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("failed to inspect exec session: %v", err)})
+			return
+		}
+
+		name, err := h.cli.ContainerName(c, inspect.ContainerID)
+		if err != nil {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+
+		email, role := currentUser(c)
+		if !h.acl.Allowed(email, role, name, models.ContainerActionConsole) || !middleware.TokenAllows(c, models.PermissionContainersConsole) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		c.JSON(200, inspect)
+	}
+}
+
+func (h *DockerHandler) streamExecSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		execID := c.Param("execId")
+
+		// Only sessions created through this API can be attached, only once and only by their creator
+		value, ok := h.execSessions.Load(execID)
+		if !ok || value.(execSession).owner != c.GetString("userEmail") || time.Since(value.(execSession).createdAt) > EXEC_SESSION_TTL {
+			c.JSON(http.StatusNotFound, gin.H{"error": "exec session not found"})
+			return
+		}
+		if _, ok := h.execSessions.LoadAndDelete(execID); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "exec session not found"})
+			return
+		}
+		tty := value.(execSession).tty
+
+		// The session outlives the request context, it is closed by the bridge instead
+		stream, err := h.cli.AttachExecSession(context.Background(), execID, tty)
+		if err != nil {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("failed to start exec session: %v", err)})
+			return
+		}
+		defer stream.Close()
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var resize func(rows, cols uint) error
+		if tty {
+			resize = func(rows, cols uint) error {
+				return h.cli.ResizeExecTTY(context.Background(), execID, rows, cols)
+			}
+		}
+
+		if !bridgeTerminal(conn, stream, tty, resize) {
+			return
+		}
+
+		// Report how the command ended before closing the socket
+		exit := gin.H{"type": "exit"}
+		if inspect, err := h.cli.InspectExecSession(context.Background(), execID); err == nil {
+			exit["exitCode"] = inspect.ExitCode
+		} else {
+			exit["error"] = err.Error()
+		}
+		conn.WriteJSON(exit)
+		closeTerminal(conn)
+	}
+}
+
+func (h *DockerHandler) attachContainer() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		inspect, err := h.cli.InspectContainer(c, id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+
+		if !inspect.State.Running {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "container is not running"})
+			return
+		}
+
+		if !inspect.Config.OpenStdin {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "container was not created with stdin open, recreate it with attachStdin enabled"})
+			return
+		}
+
+		// The attach outlives the request context, it is closed by the bridge instead
+		stream, err := h.cli.AttachContainer(context.Background(), inspect.ID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to attach to container: %v", err)})
+			return
+		}
+		defer stream.Close()
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var resize func(rows, cols uint) error
+		if inspect.Config.Tty {
+			resize = func(rows, cols uint) error {
+				return h.cli.ResizeContainerTTY(context.Background(), inspect.ID, rows, cols)
+			}
+		}
+
+		if bridgeTerminal(conn, stream, inspect.Config.Tty, resize) {
+			closeTerminal(conn)
+		}
+	}
+}
+
 func (h *DockerHandler) removeImage() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
@@ -394,7 +762,21 @@ func (h *DockerHandler) updateContainer() gin.HandlerFunc {
 			return
 		}
 
-		newID, warnings, err := h.cli.UpdateContainer(c, id, &req)
+		// Renaming moves the container to other volumes and permissions, users with only
+		// an edit grant on this container may not do that
+		name, err := h.cli.ContainerName(c, id)
+		if err != nil {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+		_, role := currentUser(c)
+		if req.Name != name && (!role.Can(models.PermissionContainersEdit) || !middleware.TokenAllows(c, models.PermissionContainersEdit)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "renaming a container requires the containers.edit permission"})
+			return
+		}
+
+		// A rollback has to finish even if the client goes away
+		newID, warnings, err := h.cli.UpdateContainer(context.WithoutCancel(c), id, &req)
 		if err != nil {
 			c.JSON(400, gin.H{"error": fmt.Sprintf("failed to update container: %v", err)})
 			return
@@ -407,6 +789,32 @@ func (h *DockerHandler) updateContainer() gin.HandlerFunc {
 	}
 }
 
+// requireContainerAction aborts the request unless the user may perform action on the container in the id param
+func (h *DockerHandler) requireContainerAction(action models.ContainerAction) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name, err := h.cli.ContainerName(c, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			c.Abort()
+			return
+		}
+
+		email, role := currentUser(c)
+		if !h.acl.Allowed(email, role, name, action) || !middleware.TokenAllows(c, action.Permission()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// currentUser returns the email and role set by middleware.CheckUser
+func currentUser(c *gin.Context) (string, models.UserRole) {
+	return c.GetString("userEmail"), models.UserRole(c.GetString("userRole"))
+}
+
 func truncateID(id string) string {
 	if len(id) > 12 {
 		return id[:12]
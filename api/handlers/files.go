@@ -2,41 +2,84 @@ package handlers
 
 import (
 	"fmt"
+	"gsm/acl"
 	"gsm/config"
 	"gsm/files"
 	middleware "gsm/middleware"
+	"gsm/models"
+	"mime"
 	"net/http"
+	"os"
 	"path"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type FileHandler struct {
-	cli files.Client
+	db      *gorm.DB
+	cli     files.Client
+	acl     *acl.ACL
+	uploads *files.UploadStore
 }
 
-func NewFileHandler() (*FileHandler, error) {
+func NewFileHandler(db *gorm.DB) (*FileHandler, error) {
 	cfg := config.Get()
 
 	volumesDir := path.Join(cfg.DataDir, cfg.VolumeDir)
+	uploadsDir := path.Join(cfg.DataDir, cfg.UploadDir)
 
 	cli := files.NewClient(volumesDir)
-	return &FileHandler{cli: cli}, nil
+	h := &FileHandler{db: db, cli: cli, acl: acl.New(db), uploads: files.NewUploadStore(uploadsDir)}
+	h.removeStaleUploads()
+	return h, nil
 }
 
 // RegisterFileHandlers registers all file-related handlers with the given router group
 func (h *FileHandler) RegisterFileHandlers(rg *gin.RouterGroup) {
-	rg.Use(middleware.CheckUser, middleware.RequireUser)
+	rg.Use(middleware.CheckUser(h.db), middleware.RequireUser, middleware.RequireScope(models.PermissionContainersFiles))
 
 	// File endpoints
 	rg.GET("/", h.listFiles())
+	rg.GET("/search", h.searchFiles())
 	rg.GET("/content", h.readFile())
-	rg.POST("/content", middleware.RequireRole("admin"), h.writeFile())
-	rg.POST("/directory", middleware.RequireRole("admin"), h.createDirectory())
-	rg.DELETE("/", middleware.RequireRole("admin"), h.deletePath())
-	rg.POST("/move", middleware.RequireRole("admin"), h.movePath())
+	rg.POST("/content", h.writeFile())
+	rg.POST("/directory", h.createDirectory())
+	rg.DELETE("/", h.deletePath())
+	rg.POST("/move", h.movePath())
 	rg.GET("/download", h.downloadFile())
-	rg.POST("/upload", middleware.RequireRole("admin"), h.uploadFile())
+	rg.HEAD("/download", h.downloadFile())
+	rg.GET("/download/archive", h.downloadArchive())
+	rg.POST("/upload", h.uploadFile())
+	rg.POST("/extract", h.extractArchive())
+	rg.POST("/compress", h.compressPaths())
+
+	// Resumable upload endpoints
+	rg.POST("/uploads", h.createUpload())
+	rg.HEAD("/uploads/:uploadId", h.getUploadOffset())
+	rg.PATCH("/uploads/:uploadId", h.patchUpload())
+	rg.POST("/uploads/:uploadId/finalize", h.finalizeUpload())
+	rg.DELETE("/uploads/:uploadId", h.cancelUpload())
+}
+
+// authorize responds with 403 unless the user has the files permission on every given path.
+// The first path segment is the container's volume directory, so only admins may act on the root.
+func (h *FileHandler) authorize(c *gin.Context, paths ...string) bool {
+	email, role := currentUser(c)
+	for _, p := range paths {
+		if !h.acl.Allowed(email, role, volumeContainer(p), models.ContainerActionFiles) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return false
+		}
+	}
+	return true
+}
+
+// volumeContainer returns the name of the container owning the volume path, empty for the root
+func volumeContainer(p string) string {
+	container, _, _ := strings.Cut(strings.TrimPrefix(path.Clean("/"+p), "/"), "/")
+	return container
 }
 
 func (h *FileHandler) listFiles() gin.HandlerFunc {
@@ -46,6 +89,16 @@ func (h *FileHandler) listFiles() gin.HandlerFunc {
 			requestPath = "/"
 		}
 
+		// The root lists volume directories, users only see those of containers they may manage
+		if volumeContainer(requestPath) == "" {
+			h.listVolumes(c, requestPath)
+			return
+		}
+
+		if !h.authorize(c, requestPath) {
+			return
+		}
+
 		files, err := h.cli.ListFiles(requestPath)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -56,6 +109,33 @@ func (h *FileHandler) listFiles() gin.HandlerFunc {
 	}
 }
 
+func (h *FileHandler) listVolumes(c *gin.Context, requestPath string) {
+	email, role := currentUser(c)
+	allowed, all, err := h.acl.Containers(email, role, models.ContainerActionFiles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.cli.ListFiles(requestPath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !all {
+		visible := []files.FileInfo{}
+		for _, entry := range entries {
+			if allowed[entry.Name] {
+				visible = append(visible, entry)
+			}
+		}
+		entries = visible
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
 func (h *FileHandler) readFile() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestPath := c.Query("path")
@@ -64,6 +144,10 @@ func (h *FileHandler) readFile() gin.HandlerFunc {
 			return
 		}
 
+		if !h.authorize(c, requestPath) {
+			return
+		}
+
 		content, mime, err := h.cli.ReadFile(requestPath)
 		if err != nil {
 			if mime != "" {
@@ -93,6 +177,10 @@ func (h *FileHandler) writeFile() gin.HandlerFunc {
 			return
 		}
 
+		if !h.authorize(c, req.Path) {
+			return
+		}
+
 		err := h.cli.WriteFile(req.Path, req.Content)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -114,6 +202,10 @@ func (h *FileHandler) createDirectory() gin.HandlerFunc {
 			return
 		}
 
+		if !h.authorize(c, req.Path) {
+			return
+		}
+
 		err := h.cli.CreateDirectory(req.Path)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -132,6 +224,10 @@ func (h *FileHandler) deletePath() gin.HandlerFunc {
 			return
 		}
 
+		if !h.authorize(c, requestPath) {
+			return
+		}
+
 		err := h.cli.DeletePath(requestPath)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -154,6 +250,10 @@ func (h *FileHandler) movePath() gin.HandlerFunc {
 			return
 		}
 
+		if !h.authorize(c, req.Source, req.Destination) {
+			return
+		}
+
 		err := h.cli.MovePath(req.Source, req.Destination)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -164,6 +264,8 @@ func (h *FileHandler) movePath() gin.HandlerFunc {
 	}
 }
 
+// downloadFile serves a file with Range, If-Range, If-None-Match and If-Modified-Since support so downloads can be resumed.
+// Directories are streamed as a zip archive, which cannot be resumed.
 func (h *FileHandler) downloadFile() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestPath := c.Query("path")
@@ -172,13 +274,48 @@ func (h *FileHandler) downloadFile() gin.HandlerFunc {
 			return
 		}
 
-		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", requestPath))
-		err := h.cli.DownloadFile(requestPath, c.Writer)
+		if !h.authorize(c, requestPath) {
+			return
+		}
+
+		file, info, err := h.cli.OpenFile(requestPath)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+
+		if info.IsDir() {
+			c.Header("Content-Disposition", contentDisposition(info.Name()+".zip"))
+			c.Header("Content-Type", "application/zip")
+			if c.Request.Method == http.MethodHead {
+				c.Status(http.StatusOK)
+				return
+			}
+			if err := h.cli.DownloadFile(requestPath, c.Writer); err != nil && !c.Writer.Written() {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
 			return
 		}
+
+		// ServeContent answers conditional requests from the ETag and the modification time
+		c.Header("ETag", fileETag(info))
+		c.Header("Content-Disposition", contentDisposition(info.Name()))
+		http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), file)
+	}
+}
+
+// fileETag changes whenever the file is modified or resized
+func fileETag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
+
+// contentDisposition builds an attachment header, names that are not plain ASCII are encoded as RFC 2231 requires
+func contentDisposition(filename string) string {
+	if disposition := mime.FormatMediaType("attachment", map[string]string{"filename": filename}); disposition != "" {
+		return disposition
 	}
+	return "attachment"
 }
 
 func (h *FileHandler) uploadFile() gin.HandlerFunc {
@@ -189,6 +326,10 @@ func (h *FileHandler) uploadFile() gin.HandlerFunc {
 			return
 		}
 
+		if !h.authorize(c, destination) {
+			return
+		}
+
 		file, err := c.FormFile("file")
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "no file uploaded"})
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"gsm/registry"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IMAGE_SEARCH_LIMIT     = 25
+	IMAGE_SEARCH_MAX_LIMIT = 100
+)
+
+func (h *DockerHandler) searchImages() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		term := c.Query("term")
+		if term == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "term is required"})
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(IMAGE_SEARCH_LIMIT)))
+		if err != nil || limit <= 0 || limit > IMAGE_SEARCH_MAX_LIMIT {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", IMAGE_SEARCH_MAX_LIMIT)})
+			return
+		}
+
+		results, err := h.cli.SearchImages(c, term, limit)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, results)
+	}
+}
+
+// listImageTags lists a page of tags of ?image=, pass the returned next as ?last= for the following page
+func (h *DockerHandler) listImageTags() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		image := c.Query("image")
+		if image == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "image is required"})
+			return
+		}
+
+		n, err := strconv.Atoi(c.DefaultQuery("n", strconv.Itoa(registry.DEFAULT_PAGE_SIZE)))
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "n must be a positive number"})
+			return
+		}
+
+		tags, err := h.registry.ListTags(c, image, n, c.Query("last"))
+		if err != nil {
+			if errors.Is(err, registry.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("image %s not found", image)})
+				return
+			}
+			if errors.Is(err, registry.ErrNotAllowed) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to list tags: %v", err)})
+			return
+		}
+
+		c.JSON(http.StatusOK, tags)
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"fmt"
+	"gsm/acl"
+	"gsm/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *DockerHandler) getMyPermissions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name, err := h.cli.ContainerName(c, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+
+		email, role := currentUser(c)
+		actions, err := h.acl.Actions(email, role, name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"container": name, "actions": actions})
+	}
+}
+
+func (h *DockerHandler) listPermissions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name, err := h.cli.ContainerName(c, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+
+		permissions, err := h.acl.List(name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, permissions)
+	}
+}
+
+func (h *DockerHandler) grantPermissions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Email   string                   `json:"email" binding:"required,email"`
+			Actions []models.ContainerAction `json:"actions" binding:"required,min=1"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		for _, action := range req.Actions {
+			if !acl.IsValidAction(action) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid action: %s", action)})
+				return
+			}
+		}
+
+		name, err := h.cli.ContainerName(c, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+
+		if err := h.acl.Grant(name, req.Email, req.Actions, c.GetString("userEmail")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		permissions, err := h.acl.List(name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, permissions)
+	}
+}
+
+// revokePermissions removes the actions given in ?action=, or every action when none are given
+func (h *DockerHandler) revokePermissions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var actions []models.ContainerAction
+		for _, value := range c.QueryArray("action") {
+			for _, action := range strings.Split(value, ",") {
+				action := models.ContainerAction(strings.TrimSpace(action))
+				if !acl.IsValidAction(action) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid action: %s", action)})
+					return
+				}
+				actions = append(actions, action)
+			}
+		}
+
+		name, err := h.cli.ContainerName(c, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+
+		if err := h.acl.Revoke(name, c.Param("email"), actions); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"gsm/docker"
+	"gsm/models"
+	"gsm/query"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *DockerHandler) getQuerySettings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inspect, err := h.cli.InspectContainer(c, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+
+		var settings models.QuerySettings
+		if err := h.db.Where("container = ?", containerName(inspect)).First(&settings).Error; err != nil {
+			c.JSON(http.StatusOK, gin.H{"configured": false})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"configured": true,
+			"protocol":   settings.Protocol,
+			"port":       settings.Port,
+		})
+	}
+}
+
+func (h *DockerHandler) updateQuerySettings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Protocol string `json:"protocol" binding:"required,oneof=a2s minecraft none"`
+			Port     uint16 `json:"port"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		inspect, err := h.cli.InspectContainer(c, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+
+		name := containerName(inspect)
+
+		// "none" turns querying off for the container
+		if req.Protocol == "none" {
+			if err := h.db.Unscoped().Where("container = ?", name).Delete(&models.QuerySettings{}).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove query settings"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"configured": false})
+			return
+		}
+
+		if req.Port == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "port is required"})
+			return
+		}
+
+		settings := models.QuerySettings{Container: name}
+		if err := h.db.Where(&settings).FirstOrInit(&settings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		settings.Protocol = req.Protocol
+		settings.Port = req.Port
+		if err := h.db.Save(&settings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save query settings"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"configured": true,
+			"protocol":   settings.Protocol,
+			"port":       settings.Port,
+		})
+	}
+}
+
+// queryServer returns the live status of a container with a configured query protocol, or nil
+func (h *DockerHandler) queryServer(ctx context.Context, inspect *docker.ContainerInspect) *query.Info {
+	var settings models.QuerySettings
+	if err := h.db.Where("container = ?", containerName(inspect)).First(&settings).Error; err != nil {
+		return nil
+	}
+
+	protocol := query.Protocol(settings.Protocol)
+	info := &query.Info{Protocol: protocol}
+
+	if !inspect.State.Running {
+		return info
+	}
+
+	address, err := publishedAddress(inspect, settings.Port, protocol.Network())
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	result, err := query.Query(ctx, protocol, address)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	return result
+}
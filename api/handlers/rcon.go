@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gsm/config"
+	"gsm/docker"
+	"gsm/models"
+	"gsm/rcon"
+	"gsm/secrets"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const RCON_TIMEOUT = 15 * time.Second
+
+func (h *DockerHandler) getRconSettings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inspect, err := h.cli.InspectContainer(c, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+
+		var settings models.RconSettings
+		if err := h.db.Where("container = ?", containerName(inspect)).First(&settings).Error; err != nil {
+			c.JSON(http.StatusOK, gin.H{"configured": false})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"configured": true,
+			"port":       settings.Port,
+		})
+	}
+}
+
+func (h *DockerHandler) updateRconSettings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Port     uint16 `json:"port" binding:"required,gt=0"`
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		inspect, err := h.cli.InspectContainer(c, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+
+		encrypted, err := secrets.Encrypt(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		settings := models.RconSettings{Container: containerName(inspect)}
+		if err := h.db.Where(&settings).FirstOrInit(&settings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		settings.Port = req.Port
+		settings.PasswordEncrypted = encrypted
+		if err := h.db.Save(&settings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save rcon settings"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"configured": true,
+			"port":       settings.Port,
+		})
+	}
+}
+
+func (h *DockerHandler) execRcon() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Command string `json:"command" binding:"required"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		inspect, err := h.cli.InspectContainer(c, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+
+		if !inspect.State.Running {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "container is not running"})
+			return
+		}
+
+		var settings models.RconSettings
+		if err := h.db.Where("container = ?", containerName(inspect)).First(&settings).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rcon is not configured for this container"})
+			return
+		}
+
+		// Passwords are cleared when they could not be encrypted on upgrade
+		if settings.PasswordEncrypted == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rcon password is not set, save the rcon settings again"})
+			return
+		}
+		password, err := secrets.Decrypt(settings.PasswordEncrypted)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to read rcon password: %v", err)})
+			return
+		}
+
+		// RCON always runs over TCP
+		address, err := publishedAddress(inspect, settings.Port, "tcp")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, RCON_TIMEOUT)
+		defer cancel()
+
+		client, err := rcon.Dial(ctx, address, password)
+		if err != nil {
+			if errors.Is(err, rcon.ErrAuthFailed) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "rcon authentication failed, check the stored password"})
+				return
+			}
+			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to connect to rcon: %v", err)})
+			return
+		}
+		defer client.Close()
+
+		response, err := client.Execute(req.Command)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to execute command: %v", err)})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"response": response})
+	}
+}
+
+// publishedAddress returns the address the container port is reachable on through its host port binding
+func publishedAddress(inspect *docker.ContainerInspect, port uint16, protocol string) (string, error) {
+	bindings := inspect.HostConfig.PortBindings[fmt.Sprintf("%d/%s", port, protocol)]
+	if len(bindings) == 0 {
+		return "", fmt.Errorf("port %d/%s is not published", port, protocol)
+	}
+
+	return net.JoinHostPort(config.Get().GameHost, strconv.Itoa(int(bindings[0].HostPort))), nil
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"gsm/models"
+	"gsm/secrets"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type registryRequest struct {
+	Host     string `json:"host"`
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// normalizeRegistryHost accepts hosts with a scheme or path, e.g. "https://ghcr.io/", and Docker Hub aliases
+func normalizeRegistryHost(host string) string {
+	host = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(host), "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return strings.ToLower(host)
+}
+
+func (h *DockerHandler) listRegistries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var credentials []models.RegistryCredential
+		if err := h.db.Order("host").Find(&credentials).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch registries"})
+			return
+		}
+		c.JSON(http.StatusOK, credentials)
+	}
+}
+
+func (h *DockerHandler) createRegistry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req registryRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		host := normalizeRegistryHost(req.Host)
+		if host == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "host is required"})
+			return
+		}
+
+		var existing models.RegistryCredential
+		if err := h.db.Where("host = ?", host).First(&existing).Error; err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("credentials for %s already exist", host)})
+			return
+		}
+
+		encrypted, err := secrets.Encrypt(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Reject credentials the registry does not accept instead of failing on the next pull
+		if err := h.cli.RegistryLogin(c, host, req.Username, req.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		credential := models.RegistryCredential{
+			Host:              host,
+			Username:          req.Username,
+			PasswordEncrypted: encrypted,
+			CreatedBy:         c.GetString("userEmail"),
+		}
+		if err := h.db.Create(&credential).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save registry"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, credential)
+	}
+}
+
+func (h *DockerHandler) updateRegistry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req registryRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		var credential models.RegistryCredential
+		if err := h.db.First(&credential, c.Param("registryId")).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "registry not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		encrypted, err := secrets.Encrypt(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := h.cli.RegistryLogin(c, credential.Host, req.Username, req.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		credential.Username = req.Username
+		credential.PasswordEncrypted = encrypted
+		if err := h.db.Save(&credential).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save registry"})
+			return
+		}
+
+		c.JSON(http.StatusOK, credential)
+	}
+}
+
+func (h *DockerHandler) removeRegistry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result := h.db.Unscoped().Delete(&models.RegistryCredential{}, c.Param("registryId"))
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove registry"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "registry not found"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"gsm/docker"
+	middleware "gsm/middleware"
+	"gsm/models"
+	"gsm/scheduler"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type schedulesHandler struct {
+	db        *gorm.DB
+	cli       docker.Client
+	scheduler *scheduler.Scheduler
+}
+
+type scheduleRequest struct {
+	Name      string                `json:"name"`
+	Container string                `json:"container" binding:"required"` // Name or ID, stored as the name
+	Action    models.ScheduleAction `json:"action" binding:"required,oneof=start stop restart"`
+	Spec      string                `json:"spec" binding:"required"`
+	Enabled   *bool                 `json:"enabled"` // Defaults to true when omitted
+}
+
+func NewSchedulesHandler(db *gorm.DB, scheduler *scheduler.Scheduler) (*schedulesHandler, error) {
+	cli, err := NewDockerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &schedulesHandler{db: db, cli: cli, scheduler: scheduler}, nil
+}
+
+// RegisterSchedulesRoutes registers all schedule-related handlers with the given router group
+func (h *schedulesHandler) RegisterSchedulesRoutes(rg *gin.RouterGroup) {
+	rg.Use(middleware.CheckUser(h.db), middleware.RequireUser, middleware.RequirePermission(models.PermissionSchedulesManage))
+
+	rg.GET("/", h.listSchedules)
+	rg.POST("/", h.createSchedule)
+	rg.GET("/:id", h.getSchedule)
+	rg.PUT("/:id", h.updateSchedule)
+	rg.DELETE("/:id", h.deleteSchedule)
+	rg.POST("/:id/run", h.runSchedule)
+}
+
+func (h *schedulesHandler) listSchedules(c *gin.Context) {
+	query := h.db.Order("id")
+	if container := c.Query("container"); container != "" {
+		query = query.Where("container = ?", container)
+	}
+
+	var schedules []models.Schedule
+	if err := query.Find(&schedules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules"})
+		return
+	}
+
+	for i := range schedules {
+		schedules[i].NextRunAt = h.scheduler.NextRun(schedules[i].ID)
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+func (h *schedulesHandler) getSchedule(c *gin.Context) {
+	schedule, ok := h.findSchedule(c)
+	if !ok {
+		return
+	}
+
+	schedule.NextRunAt = h.scheduler.NextRun(schedule.ID)
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *schedulesHandler) createSchedule(c *gin.Context) {
+	var req scheduleRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := scheduler.ValidateSpec(req.Spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule := models.Schedule{CreatedBy: c.GetString("userEmail")}
+	if err := req.applyTo(c, h.cli, &schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Create(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
+		return
+	}
+
+	if err := h.scheduler.Register(schedule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule.NextRunAt = h.scheduler.NextRun(schedule.ID)
+	c.JSON(http.StatusCreated, schedule)
+}
+
+func (h *schedulesHandler) updateSchedule(c *gin.Context) {
+	schedule, ok := h.findSchedule(c)
+	if !ok {
+		return
+	}
+
+	var req scheduleRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := scheduler.ValidateSpec(req.Spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := req.applyTo(c, h.cli, &schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Save(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update schedule"})
+		return
+	}
+
+	if err := h.scheduler.Register(schedule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule.NextRunAt = h.scheduler.NextRun(schedule.ID)
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *schedulesHandler) deleteSchedule(c *gin.Context) {
+	schedule, ok := h.findSchedule(c)
+	if !ok {
+		return
+	}
+
+	h.scheduler.Unregister(schedule.ID)
+
+	if err := h.db.Unscoped().Delete(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *schedulesHandler) runSchedule(c *gin.Context) {
+	schedule, ok := h.findSchedule(c)
+	if !ok {
+		return
+	}
+
+	if err := h.scheduler.Run(schedule.ID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *schedulesHandler) findSchedule(c *gin.Context) (models.Schedule, bool) {
+	var schedule models.Schedule
+	if err := h.db.First(&schedule, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return schedule, false
+	}
+	return schedule, true
+}
+
+// applyTo copies the request onto the schedule, resolving the container to its name
+func (r *scheduleRequest) applyTo(ctx context.Context, cli docker.Client, schedule *models.Schedule) error {
+	name, err := cli.ContainerName(ctx, r.Container)
+	if err != nil {
+		return fmt.Errorf("Container %s not found", r.Container)
+	}
+
+	schedule.Name = r.Name
+	schedule.Container = name
+	schedule.Action = r.Action
+	schedule.Spec = r.Spec
+	schedule.Enabled = r.Enabled == nil || *r.Enabled
+	return nil
+}
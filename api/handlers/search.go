@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gsm/config"
+	"gsm/files"
+	"gsm/models"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchFiles searches the volumes recursively, streaming each match as a server-sent event and a final
+// event with "done" and the summary or "error". Supported filters: path, name (glob), minSize, maxSize,
+// modifiedAfter and modifiedBefore (RFC3339), content with regex and ignoreCase, and limit.
+func (h *FileHandler) searchFiles() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := config.Get()
+		options := files.SearchOptions{
+			Name:        c.Query("name"),
+			Content:     c.Query("content"),
+			Regex:       c.Query("regex") == "true",
+			IgnoreCase:  c.Query("ignoreCase") == "true",
+			MaxResults:  cfg.SearchMaxResults,
+			MaxFileSize: cfg.SearchMaxFileSize,
+		}
+
+		for param, target := range map[string]*int64{"minSize": &options.MinSize, "maxSize": &options.MaxSize} {
+			if value := c.Query(param); value != "" {
+				size, err := strconv.ParseInt(value, 10, 64)
+				if err != nil || size < 0 {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s", param)})
+					return
+				}
+				*target = size
+			}
+		}
+		for param, target := range map[string]*time.Time{"modifiedAfter": &options.ModifiedAfter, "modifiedBefore": &options.ModifiedBefore} {
+			if value := c.Query(param); value != "" {
+				t, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s time, expected RFC3339", param)})
+					return
+				}
+				*target = t
+			}
+		}
+		if value := c.Query("limit"); value != "" {
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+				return
+			}
+			// The configured limit is an upper bound
+			if options.MaxResults <= 0 || limit < options.MaxResults {
+				options.MaxResults = limit
+			}
+		}
+
+		if err := options.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		paths, ok := h.searchPaths(c, c.DefaultQuery("path", "/"))
+		if !ok {
+			return
+		}
+		options.Paths = paths
+
+		ctx := c.Request.Context()
+		if cfg.SearchTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.SearchTimeout)
+			defer cancel()
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Writer.WriteHeader(http.StatusOK)
+
+		send := func(data interface{}) {
+			if eventJSON, err := json.Marshal(data); err == nil {
+				c.Writer.Write([]byte("data: " + string(eventJSON) + "\n\n"))
+				c.Writer.Flush()
+			}
+		}
+
+		summary, err := h.cli.Search(ctx, options, func(result files.SearchResult) {
+			send(result)
+		})
+		if err != nil {
+			send(gin.H{"error": err.Error()})
+			return
+		}
+
+		send(gin.H{
+			"done":      true,
+			"files":     summary.Files,
+			"matches":   summary.Matches,
+			"truncated": summary.Truncated,
+			"timedOut":  summary.TimedOut,
+		})
+	}
+}
+
+// searchPaths returns the directories a search of requestPath covers. Searching the root covers only the
+// volumes of containers the user may manage, other paths require the files permission on their container.
+func (h *FileHandler) searchPaths(c *gin.Context, requestPath string) ([]string, bool) {
+	if volumeContainer(requestPath) != "" {
+		return []string{requestPath}, h.authorize(c, requestPath)
+	}
+
+	email, role := currentUser(c)
+	allowed, all, err := h.acl.Containers(email, role, models.ContainerActionFiles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if all {
+		return []string{requestPath}, true
+	}
+
+	entries, err := h.cli.ListFiles(requestPath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	paths := []string{}
+	for _, entry := range entries {
+		if entry.IsDir && allowed[entry.Name] {
+			paths = append(paths, path.Join("/", entry.Name))
+		}
+	}
+	return paths, true
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"gsm/config"
+	"gsm/models"
+	"net/http"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const REFRESH_COOKIE_PATH = "/auth" // The refresh token is only sent to the auth endpoints
+
+var errSessionInvalid = errors.New("session is invalid")
+
+// startSession creates a session for the user and sets the access and refresh token cookies
+func (h *authHandler) startSession(c *gin.Context, user models.User) error {
+	cfg := config.Get()
+
+	refreshToken, refreshHash, err := models.NewTokenSecret(models.REFRESH_TOKEN_PREFIX)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		JTI:         generateState(),
+		Email:       user.Email,
+		RefreshHash: refreshHash,
+		IssuedAt:    now,
+		ExpiresAt:   now.Add(cfg.SessionTTL),
+		UserAgent:   c.Request.UserAgent(),
+		ClientIP:    c.ClientIP(),
+	}
+	if err := h.db.Create(&session).Error; err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+
+	// Expired sessions are of no further use
+	h.db.Where("email = ? AND expires_at < ?", user.Email, now).Delete(&models.Session{})
+
+	return h.setSessionCookies(c, &session, user, refreshToken)
+}
+
+// rotateSession exchanges a refresh token for a new access and refresh token.
+// Presenting an already rotated refresh token revokes the session, as the token has likely been stolen.
+func (h *authHandler) rotateSession(c *gin.Context, refreshToken string) error {
+	hash := models.HashToken(refreshToken)
+
+	var session models.Session
+	if err := h.db.Where("refresh_hash = ?", hash).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			var reused models.Session
+			if err := h.db.Where("previous_refresh_hash = ?", hash).First(&reused).Error; err == nil {
+				revokeSession(h.db, &reused, "refresh token reused")
+			}
+			return errSessionInvalid
+		}
+		return fmt.Errorf("failed to fetch session: %v", err)
+	}
+
+	if !session.Active() {
+		return errSessionInvalid
+	}
+
+	// The role is read again so the access token reflects the current allowed user
+	var allowedUser models.AllowedUser
+	if err := h.db.Where("email = ?", session.Email).First(&allowedUser).Error; err != nil {
+		revokeSession(h.db, &session, "user removed")
+		return errSessionInvalid
+	}
+
+	var user models.User
+	if err := h.db.Where("email = ?", session.Email).First(&user).Error; err != nil {
+		return errSessionInvalid
+	}
+	user.Role = allowedUser.Role
+
+	newRefreshToken, newRefreshHash, err := models.NewTokenSecret(models.REFRESH_TOKEN_PREFIX)
+	if err != nil {
+		return err
+	}
+
+	// Only rotate if nobody else rotated the same token concurrently
+	now := time.Now()
+	result := h.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_hash = ?", session.ID, hash).
+		Updates(map[string]interface{}{
+			"refresh_hash":          newRefreshHash,
+			"previous_refresh_hash": hash,
+			"refreshed_at":          now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to rotate session: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errSessionInvalid
+	}
+
+	return h.setSessionCookies(c, &session, user, newRefreshToken)
+}
+
+func (h *authHandler) setSessionCookies(c *gin.Context, session *models.Session, user models.User, refreshToken string) error {
+	cfg := config.Get()
+
+	// Generate a short-lived JWT bound to the session
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":      user.ID,
+		"jti":     session.JTI,
+		"email":   user.Email,
+		"role":    user.Role,
+		"picture": user.Picture,
+		"exp":     time.Now().Add(cfg.AccessTokenTTL).Unix(),
+	})
+	tokenString, err := jwtToken.SignedString([]byte(cfg.JWTSecret))
+	if err != nil {
+		return fmt.Errorf("failed to sign token: %v", err)
+	}
+
+	c.SetSameSite(h.sameSite)
+	c.SetCookie("token", tokenString, int(cfg.AccessTokenTTL.Seconds()), "/", cfg.CookieDomain, h.secure, true)
+	c.SetCookie("refresh_token", refreshToken, int(time.Until(session.ExpiresAt).Seconds()), REFRESH_COOKIE_PATH, cfg.CookieDomain, h.secure, true)
+	return nil
+}
+
+func (h *authHandler) clearSessionCookies(c *gin.Context) {
+	cfg := config.Get()
+	c.SetSameSite(h.sameSite)
+	c.SetCookie("token", "", -1, "/", cfg.CookieDomain, h.secure, true) // Set cookie with an expired date
+	c.SetCookie("refresh_token", "", -1, REFRESH_COOKIE_PATH, cfg.CookieDomain, h.secure, true)
+}
+
+func (h *authHandler) refresh(c *gin.Context) {
+	refreshToken, err := c.Cookie("refresh_token")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token missing"})
+		return
+	}
+
+	if err := h.rotateSession(c, refreshToken); err != nil {
+		if errors.Is(err, errSessionInvalid) {
+			h.clearSessionCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not refresh session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session refreshed"})
+}
+
+func revokeSession(db *gorm.DB, session *models.Session, reason string) error {
+	return db.Model(session).Where("revoked_at IS NULL").Updates(map[string]interface{}{
+		"revoked_at":     time.Now(),
+		"revoked_reason": reason,
+	}).Error
+}
+
+// revokeSessions revokes every active session of the user, access tokens stop working on their next request
+func revokeSessions(db *gorm.DB, email, reason string) error {
+	err := db.Model(&models.Session{}).
+		Where("email = ? AND revoked_at IS NULL", email).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"fmt"
+	"gsm/docker"
+	middleware "gsm/middleware"
+	"gsm/models"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type templatesHandler struct {
+	db  *gorm.DB
+	cli docker.Client
+}
+
+type templateRequest struct {
+	Name        string                 `json:"name" binding:"required"`
+	Description string                 `json:"description"`
+	Config      docker.ContainerCreate `json:"config"`
+}
+
+// templateOverrides holds the per-instance values applied on top of a template
+type templateOverrides struct {
+	Name      string            `json:"name"`
+	HostPorts map[string]uint16 `json:"hostPorts"` // containerPort/protocol -> host port
+	Env       []string          `json:"env"`       // KEY=VALUE, replaces matching template keys
+}
+
+func NewTemplatesHandler(db *gorm.DB) (*templatesHandler, error) {
+	cli, err := NewDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	return &templatesHandler{db: db, cli: cli}, nil
+}
+
+// RegisterTemplatesRoutes registers all template-related handlers with the given router group
+func (h *templatesHandler) RegisterTemplatesRoutes(rg *gin.RouterGroup) {
+	rg.Use(middleware.CheckUser(h.db), middleware.RequireUser, middleware.RequirePermission(models.PermissionTemplatesManage))
+
+	rg.GET("/", h.listTemplates)
+	rg.POST("/", h.createTemplate)
+	rg.GET("/:id", h.getTemplate)
+	rg.PUT("/:id", h.updateTemplate)
+	rg.DELETE("/:id", h.deleteTemplate)
+	rg.POST("/:id/instantiate", h.instantiateTemplate)
+}
+
+func (h *templatesHandler) listTemplates(c *gin.Context) {
+	var templates []models.ContainerTemplate
+	if err := h.db.Order("name").Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch templates"})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *templatesHandler) getTemplate(c *gin.Context) {
+	template, ok := h.findTemplate(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *templatesHandler) createTemplate(c *gin.Context) {
+	var req templateRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	// Check if template already exists
+	var existing models.ContainerTemplate
+	if err := h.db.Where("name = ?", req.Name).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Template already exists"})
+		return
+	}
+
+	template := models.ContainerTemplate{
+		Name:        req.Name,
+		Description: req.Description,
+		Config:      req.Config,
+		CreatedBy:   c.GetString("userEmail"),
+	}
+
+	if err := h.db.Create(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create template"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+func (h *templatesHandler) updateTemplate(c *gin.Context) {
+	template, ok := h.findTemplate(c)
+	if !ok {
+		return
+	}
+
+	var req templateRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	// Don't allow renaming onto another template
+	var existing models.ContainerTemplate
+	if err := h.db.Where("name = ? AND id <> ?", req.Name, template.ID).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Template already exists"})
+		return
+	}
+
+	template.Name = req.Name
+	template.Description = req.Description
+	template.Config = req.Config
+
+	if err := h.db.Save(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *templatesHandler) deleteTemplate(c *gin.Context) {
+	template, ok := h.findTemplate(c)
+	if !ok {
+		return
+	}
+
+	// Use Unscoped().Delete() for hard deletion so the name can be reused
+	if err := h.db.Unscoped().Delete(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete template"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *templatesHandler) instantiateTemplate(c *gin.Context) {
+	template, ok := h.findTemplate(c)
+	if !ok {
+		return
+	}
+
+	var overrides templateOverrides
+	// An empty body instantiates the template as-is
+	if err := c.ShouldBindJSON(&overrides); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	createConfig, err := applyTemplateOverrides(template.Config, overrides)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, warnings, err := h.cli.CreateContainer(c, createConfig)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to create container: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":       id,
+		"warnings": warnings,
+	})
+}
+
+func (h *templatesHandler) findTemplate(c *gin.Context) (models.ContainerTemplate, bool) {
+	var template models.ContainerTemplate
+	if err := h.db.First(&template, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return template, false
+	}
+	return template, true
+}
+
+// applyTemplateOverrides returns a copy of the template config with the per-instance overrides merged in
+func applyTemplateOverrides(base docker.ContainerCreate, overrides templateOverrides) (*docker.ContainerCreate, error) {
+	createConfig := base
+
+	if overrides.Name != "" {
+		createConfig.Name = overrides.Name
+	}
+
+	createConfig.Ports = make([]docker.PortMapping, len(base.Ports))
+	copy(createConfig.Ports, base.Ports)
+	for key, hostPort := range overrides.HostPorts {
+		found := false
+		for i, port := range createConfig.Ports {
+			if key == fmt.Sprintf("%d/%s", port.ContainerPort, strings.ToLower(port.Protocol)) {
+				createConfig.Ports[i].HostPort = hostPort
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("port %s is not defined in template", key)
+		}
+	}
+
+	createConfig.Env = mergeEnv(base.Env, overrides.Env)
+
+	return &createConfig, nil
+}
+
+// mergeEnv replaces variables in base with the ones from overrides and appends new ones
+func mergeEnv(base, overrides []string) []string {
+	env := make([]string, len(base))
+	copy(env, base)
+
+	for _, override := range overrides {
+		key := strings.SplitN(override, "=", 2)[0]
+		replaced := false
+		for i, existing := range env {
+			if strings.SplitN(existing, "=", 2)[0] == key {
+				env[i] = override
+				replaced = true
+			}
+		}
+		if !replaced {
+			env = append(env, override)
+		}
+	}
+
+	return env
+}
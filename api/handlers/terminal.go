@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gsm/config"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	TERMINAL_PING_INTERVAL = 30 * time.Second
+	TERMINAL_WRITE_TIMEOUT = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || strings.EqualFold(origin, config.Get().AllowOrigin)
+	},
+}
+
+// terminalMessage is sent by the client over the websocket as a text message
+type terminalMessage struct {
+	Type string `json:"type"`           // "input" or "resize"
+	Data string `json:"data,omitempty"` // Raw input for "input" messages
+	Cols uint   `json:"cols,omitempty"` // Terminal width for "resize" messages
+	Rows uint   `json:"rows,omitempty"` // Terminal height for "resize" messages
+}
+
+// wsWriter forwards everything written to it as binary websocket messages
+type wsWriter struct {
+	mu   *sync.Mutex
+	conn *websocket.Conn
+}
+
+func (w *wsWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.conn.SetWriteDeadline(time.Now().Add(TERMINAL_WRITE_TIMEOUT))
+	if err := w.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// bridgeTerminal copies data between the websocket and the hijacked docker stream until either side closes.
+// Output is sent as binary messages, input and resize events are read as JSON text messages.
+// It returns true when the docker side ended, leaving the websocket open for a final message.
+func bridgeTerminal(conn *websocket.Conn, stream types.HijackedResponse, tty bool, resize func(rows, cols uint) error) bool {
+	var mu sync.Mutex
+	writer := &wsWriter{mu: &mu, conn: conn}
+	done := make(chan struct{})
+	clientGone := make(chan struct{})
+
+	// Docker -> websocket
+	go func() {
+		defer close(done)
+		if tty {
+			io.Copy(writer, stream.Reader)
+		} else {
+			// Without a TTY stdout and stderr are multiplexed
+			stdcopy.StdCopy(writer, writer, stream.Reader)
+		}
+	}()
+
+	// Websocket -> docker, closing the stream once the client goes away also ends the output copy
+	go func() {
+		defer stream.Close()
+		defer close(clientGone)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var msg terminalMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				continue
+			}
+
+			switch msg.Type {
+			case "input":
+				if _, err := stream.Conn.Write([]byte(msg.Data)); err != nil {
+					return
+				}
+			case "resize":
+				if resize != nil && msg.Rows > 0 && msg.Cols > 0 {
+					resize(msg.Rows, msg.Cols)
+				}
+			}
+		}
+	}()
+
+	ping := time.NewTicker(TERMINAL_PING_INTERVAL)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-done:
+			select {
+			case <-clientGone:
+				return false
+			default:
+				return true
+			}
+		case <-ping.C:
+			mu.Lock()
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(TERMINAL_WRITE_TIMEOUT))
+			mu.Unlock()
+			if err != nil {
+				return false
+			}
+		}
+	}
+}
+
+// closeTerminal sends a normal close frame to the client
+func closeTerminal(conn *websocket.Conn) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(TERMINAL_WRITE_TIMEOUT))
+}
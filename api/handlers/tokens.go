@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	middleware "gsm/middleware"
+	"gsm/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type tokensHandler struct {
+	db *gorm.DB
+}
+
+func NewTokensHandler(db *gorm.DB) *tokensHandler {
+	return &tokensHandler{db: db}
+}
+
+func (h *tokensHandler) RegisterTokensRoutes(rg *gin.RouterGroup) {
+	rg.Use(middleware.CheckUser(h.db), middleware.RequireUser, requireSession)
+
+	rg.GET("/", h.listTokens)
+	rg.POST("/", h.createToken)
+	rg.DELETE("/:id", h.revokeToken)
+}
+
+// requireSession rejects requests authenticated with an API token, so tokens cannot mint new tokens
+func requireSession(c *gin.Context) {
+	if _, exists := c.Get("tokenID"); exists {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API tokens cannot manage tokens"})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+func (h *tokensHandler) listTokens(c *gin.Context) {
+	var tokens []models.APIToken
+	if err := h.db.Where("email = ?", c.GetString("userEmail")).Order("created_at desc").Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *tokensHandler) createToken(c *gin.Context) {
+	var req struct {
+		Name      string              `json:"name" binding:"required"`
+		Scopes    []models.Permission `json:"scopes" binding:"required,min=1"`
+		ExpiresAt *time.Time          `json:"expiresAt"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !models.IsValidPermission(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope: " + string(scope)})
+			return
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+
+	secret, hash, err := models.NewTokenSecret(models.API_TOKEN_PREFIX)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	token := models.APIToken{
+		Name:      req.Name,
+		Email:     c.GetString("userEmail"),
+		Hash:      hash,
+		Hint:      secret[len(secret)-4:],
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := h.db.Create(&token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	// The secret is only ever returned here
+	c.JSON(http.StatusCreated, gin.H{
+		"token":  token,
+		"secret": secret,
+	})
+}
+
+func (h *tokensHandler) revokeToken(c *gin.Context) {
+	result := h.db.Unscoped().Where("id = ? AND email = ?", c.Param("id"), c.GetString("userEmail")).Delete(&models.APIToken{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
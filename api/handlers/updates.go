@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getImageUpdate returns whether the registry has a newer image for the container, ?refresh=true checks now
+func (h *DockerHandler) getImageUpdate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inspect, err := h.cli.InspectContainer(c, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+
+		if c.Query("refresh") != "true" {
+			if status, ok := h.updates.Status(inspect.ID); ok {
+				c.JSON(http.StatusOK, status)
+				return
+			}
+		}
+
+		status, err := h.updates.Check(c, inspect.ID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to check for update: %v", err)})
+			return
+		}
+
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// upgradeContainer pulls the container's image and recreates the container with its current configuration
+func (h *DockerHandler) upgradeContainer() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inspect, err := h.cli.InspectContainer(c, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to inspect container: %v", err)})
+			return
+		}
+
+		createConfig, err := h.cli.ContainerCreateConfig(c, inspect.ID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to read container configuration: %v", err)})
+			return
+		}
+
+		if err := h.pullAndWait(c, createConfig.Image); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to pull image: %v", err)})
+			return
+		}
+
+		// Once the old container is stopped the upgrade has to finish even if the client goes away
+		ctx := context.WithoutCancel(c)
+
+		newID, warnings, err := h.cli.UpdateContainer(ctx, inspect.ID, createConfig)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to upgrade container: %v", err)})
+			return
+		}
+		h.updates.Forget(inspect.ID)
+
+		status, err := h.updates.Check(ctx, newID)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"id": newID, "warnings": warnings})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"id":       newID,
+			"warnings": warnings,
+			"update":   status,
+		})
+	}
+}
+
+// pullAndWait pulls the image with the stored registry credentials and returns once the pull has finished
+func (h *DockerHandler) pullAndWait(ctx context.Context, imageName string) error {
+	registryAuth, err := h.credentials.PullAuth(imageName)
+	if err != nil {
+		return err
+	}
+
+	pullStream, err := h.cli.PullImage(ctx, imageName, registryAuth)
+	if err != nil {
+		return err
+	}
+	defer pullStream.Close()
+
+	// Pull errors are reported inside the progress stream
+	scanner := bufio.NewScanner(pullStream)
+	for scanner.Scan() {
+		var progress struct {
+			ErrorDetail *struct {
+				Message string `json:"message"`
+			} `json:"errorDetail"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &progress); err != nil {
+			continue
+		}
+		if progress.ErrorDetail != nil {
+			return errors.New(progress.ErrorDetail.Message)
+		}
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		return fmt.Errorf("pull stream error: %v", err)
+	}
+	return nil
+}
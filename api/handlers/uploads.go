@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"gsm/config"
+	"gsm/files"
+	"gsm/models"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	UPLOAD_EXPIRY       = 24 * time.Hour // Uploads without a chunk for this long are removed
+	UPLOAD_CONTENT_TYPE = "application/offset+octet-stream"
+)
+
+// Resumable uploads follow the tus protocol: the client creates an upload with its total size, sends the file
+// in PATCH chunks carrying the Upload-Offset they start at, asks for the current offset with HEAD after an
+// interruption and finalizes the upload once every byte has been received.
+
+type createUploadRequest struct {
+	Path     string `json:"path" binding:"required"`     // Destination directory
+	Filename string `json:"filename" binding:"required"` // Name of the file in the destination directory
+	Size     int64  `json:"size" binding:"gte=0"`
+}
+
+func (h *FileHandler) createUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req createUploadRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if !h.authorize(c, req.Path) {
+			return
+		}
+
+		if maxSize := config.Get().UploadMaxSize; maxSize > 0 && req.Size > maxSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("upload exceeds the maximum size of %d bytes", maxSize)})
+			return
+		}
+
+		if _, err := h.cli.FilePath(req.Path, req.Filename); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		h.removeStaleUploads()
+
+		upload := models.FileUpload{
+			ID:       generateState(),
+			Email:    c.GetString("userEmail"),
+			Path:     req.Path,
+			Filename: req.Filename,
+			Size:     req.Size,
+		}
+		if err := h.uploads.Create(upload.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := h.db.Create(&upload).Error; err != nil {
+			h.uploads.Remove(upload.ID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save upload"})
+			return
+		}
+
+		c.Header("Location", path.Join(c.FullPath(), upload.ID))
+		setUploadHeaders(c, upload, 0)
+		c.JSON(http.StatusCreated, gin.H{"id": upload.ID, "offset": 0, "size": upload.Size})
+	}
+}
+
+// getUploadOffset reports how much of the upload has been received in the Upload-Offset header
+func (h *FileHandler) getUploadOffset() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		upload, ok := h.findUpload(c)
+		if !ok {
+			return
+		}
+
+		offset, err := h.uploads.Offset(upload.ID)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		setUploadHeaders(c, upload, offset)
+		c.Status(http.StatusOK)
+	}
+}
+
+// patchUpload appends the request body at the Upload-Offset header
+func (h *FileHandler) patchUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.ContentType() != UPLOAD_CONTENT_TYPE {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("content type must be %s", UPLOAD_CONTENT_TYPE)})
+			return
+		}
+
+		offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Upload-Offset header"})
+			return
+		}
+
+		upload, ok := h.findUpload(c)
+		if !ok {
+			return
+		}
+
+		offset, err = h.uploads.Append(upload.ID, offset, upload.Size, c.Request.Body)
+		setUploadHeaders(c, upload, offset)
+		switch {
+		case errors.Is(err, files.ErrOffsetMismatch):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "offset": offset})
+			return
+		case errors.Is(err, files.ErrUploadTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error(), "offset": offset})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "offset": offset})
+			return
+		}
+
+		h.db.Model(&upload).Update("updated_at", time.Now())
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// finalizeUpload moves a complete upload into its destination directory
+func (h *FileHandler) finalizeUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		upload, ok := h.findUpload(c)
+		if !ok {
+			return
+		}
+
+		// Permissions may have changed since the upload was created
+		if !h.authorize(c, upload.Path) {
+			return
+		}
+
+		offset, err := h.uploads.Offset(upload.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if offset != upload.Size {
+			setUploadHeaders(c, upload, offset)
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("upload is incomplete, received %d of %d bytes", offset, upload.Size), "offset": offset})
+			return
+		}
+
+		fullPath, err := h.cli.FilePath(upload.Path, upload.Filename)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := h.uploads.Complete(upload.ID, fullPath); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		h.db.Delete(&upload)
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "file uploaded successfully",
+			"path":    path.Join(upload.Path, upload.Filename),
+		})
+	}
+}
+
+// cancelUpload discards an upload and its received data
+func (h *FileHandler) cancelUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		upload, ok := h.findUpload(c)
+		if !ok {
+			return
+		}
+
+		if err := h.uploads.Remove(upload.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		h.db.Delete(&upload)
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// findUpload loads the upload in the uploadId param, uploads of other users are not found
+func (h *FileHandler) findUpload(c *gin.Context) (models.FileUpload, bool) {
+	var upload models.FileUpload
+	err := h.db.Where("id = ? AND email = ?", c.Param("uploadId"), c.GetString("userEmail")).First(&upload).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "upload not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		}
+		return upload, false
+	}
+	return upload, true
+}
+
+// removeStaleUploads removes uploads that have not received a chunk within UPLOAD_EXPIRY, along with staged data
+// no upload refers to, e.g. left behind by a crash while an upload was created
+func (h *FileHandler) removeStaleUploads() {
+	expiry := time.Now().Add(-UPLOAD_EXPIRY)
+
+	var stale []models.FileUpload
+	if err := h.db.Where("updated_at < ?", expiry).Find(&stale).Error; err != nil {
+		log.Printf("Failed to fetch stale uploads: %v", err)
+		return
+	}
+
+	for _, upload := range stale {
+		if err := h.uploads.Remove(upload.ID); err != nil {
+			log.Printf("Failed to remove stale upload %s: %v", upload.ID, err)
+			continue
+		}
+		h.db.Delete(&upload)
+	}
+
+	ids, err := h.uploads.Stale(expiry)
+	if err != nil {
+		log.Printf("Failed to fetch staged uploads: %v", err)
+		return
+	}
+	for _, id := range ids {
+		var count int64
+		if err := h.db.Model(&models.FileUpload{}).Where("id = ?", id).Count(&count).Error; err != nil || count > 0 {
+			continue
+		}
+		if err := h.uploads.Remove(id); err != nil {
+			log.Printf("Failed to remove orphaned upload %s: %v", id, err)
+		}
+	}
+}
+
+func setUploadHeaders(c *gin.Context, upload models.FileUpload, offset int64) {
+	c.Header("Upload-Offset", strconv.FormatInt(offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Size, 10))
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"gsm/auth"
 	middleware "gsm/middleware"
 	"gsm/models"
 	"net/http"
@@ -18,11 +19,15 @@ func NewUsersHandler(db *gorm.DB) *usersHandler {
 }
 
 func (h *usersHandler) RegisterUsersRoutes(rg *gin.RouterGroup) {
-	rg.Use(middleware.CheckUser, middleware.RequireUser, middleware.RequireRole("admin"))
+	rg.Use(middleware.CheckUser(h.db), middleware.RequireUser, middleware.RequirePermission(models.PermissionUsersManage))
 
 	rg.GET("/", h.listAllowedUsers)
 	rg.POST("/", h.addAllowedUser)
+	rg.PUT("/:email", h.updateAllowedUser)
+	rg.PUT("/:email/password", h.setPassword)
 	rg.DELETE("/:email", h.removeAllowedUser)
+	rg.GET("/:email/sessions", h.listSessions)
+	rg.DELETE("/:email/sessions", h.revokeUserSessions)
 }
 
 func (h *usersHandler) listAllowedUsers(c *gin.Context) {
@@ -46,6 +51,13 @@ func (h *usersHandler) addAllowedUser(c *gin.Context) {
 		user.Role = models.UserRoleDefault
 	}
 
+	role, ok := models.ParseUserRole(string(user.Role))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+	user.Role = role
+
 	// Check if user already exists
 	var existingUser models.AllowedUser
 	if err := h.db.Where("email = ?", user.Email).First(&existingUser).Error; err == nil {
@@ -87,5 +99,131 @@ func (h *usersHandler) removeAllowedUser(c *gin.Context) {
 		return
 	}
 
+	if err := revokeSessions(h.db, email, "user removed"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *usersHandler) updateAllowedUser(c *gin.Context) {
+	var req struct {
+		Role models.UserRole `json:"role" binding:"required"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	role, ok := models.ParseUserRole(string(req.Role))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+
+	email := c.Param("email")
+
+	var user models.AllowedUser
+	if err := h.db.Where("email = ?", email).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// A role set by hand is no longer managed by the provider that created the user
+	if user.Role == role {
+		if user.ManagedBy != "" {
+			user.ManagedBy = ""
+			if err := h.db.Save(&user).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+				return
+			}
+		}
+		c.JSON(http.StatusOK, user)
+		return
+	}
+
+	// Don't allow demoting the last admin
+	if user.Role == models.UserRoleAdmin {
+		var adminCount int64
+		h.db.Model(&models.AllowedUser{}).Where("role = ?", models.UserRoleAdmin).Count(&adminCount)
+		if adminCount <= 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot demote the last admin user"})
+			return
+		}
+	}
+
+	user.Role = role
+	user.ManagedBy = ""
+	if err := h.db.Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	// Access tokens carry the role, sign the user out so the new role takes effect
+	if err := revokeSessions(h.db, email, "role changed"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *usersHandler) listSessions(c *gin.Context) {
+	var sessions []models.Session
+	if err := h.db.Where("email = ?", c.Param("email")).Order("issued_at desc").Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *usersHandler) revokeUserSessions(c *gin.Context) {
+	if err := revokeSessions(h.db, c.Param("email"), "revoked by "+c.GetString("userEmail")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// setPassword sets the user's password for the local provider, an empty password removes it
+func (h *usersHandler) setPassword(c *gin.Context) {
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	email := c.Param("email")
+
+	var user models.AllowedUser
+	if err := h.db.Where("email = ?", email).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	hash := ""
+	if req.Password != "" {
+		var err error
+		if hash, err = auth.HashPassword(req.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.db.Model(&user).Update("password_hash", hash).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+
+	// Sessions started with the old password should not outlive it
+	if err := revokeSessions(h.db, email, "password changed"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
 	c.Status(http.StatusOK)
 }
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"gsm/auth"
 	"gsm/config"
+	"gsm/docker"
 	handlers "gsm/handlers"
+	middleware "gsm/middleware"
 	"log"
 	"path"
 	"strings"
 
 	"gsm/models"
+	"gsm/registry"
+	"gsm/scheduler"
+	"gsm/secrets"
+	"gsm/updates"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -29,21 +38,101 @@ func main() {
 		db.Create(&models.AllowedUser{Email: cfg.AdminEmail, Role: "admin"})
 		log.Println("Allowed admin user created")
 	}
+	setAdminPassword(db)
+
+	if cfg.EncryptionKey == "" {
+		log.Println("Warning: ENCRYPTION_KEY is not set and JWT_SECRET is the default, registry and RCON passwords cannot be stored")
+	}
+
+	recoverContainerUpdates()
+	sched := startScheduler(db)
+	checker := startUpdateChecker(db)
 
 	r := gin.Default()
 
 	setGinMode()
 	configureCors(r)
-	registerRoutes(r, db)
+	r.Use(middleware.Audit(db))
+	registerRoutes(r, db, sched, checker)
 	startServer(r)
 }
 
+// setAdminPassword gives the admin the ADMIN_PASSWORD for the local provider, unless a password is already set
+func setAdminPassword(db *gorm.DB) {
+	cfg := config.Get()
+	if cfg.AdminPassword == "" {
+		return
+	}
+
+	hash, err := auth.HashPassword(cfg.AdminPassword)
+	if err != nil {
+		log.Fatalf("Invalid ADMIN_PASSWORD: %v", err)
+	}
+
+	result := db.Model(&models.AllowedUser{}).
+		Where("email = ? AND (password_hash IS NULL OR password_hash = '')", cfg.AdminEmail).
+		Update("password_hash", hash)
+	if result.Error != nil {
+		log.Fatalf("Failed to set admin password: %v", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Println("Admin password set")
+	}
+}
+
+// recoverContainerUpdates restores or removes containers left aside by updates interrupted by a restart
+func recoverContainerUpdates() {
+	cli, err := handlers.NewDockerClient()
+	if err != nil {
+		log.Fatalf("Failed to create docker client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), docker.UPDATE_ROLLBACK_TIMEOUT)
+	defer cancel()
+
+	actions, err := cli.RecoverUpdates(ctx)
+	for _, action := range actions {
+		log.Printf("Recovered interrupted container update: %s", action)
+	}
+	if err != nil {
+		log.Printf("Failed to recover interrupted container updates: %v", err)
+	}
+}
+
+func startScheduler(db *gorm.DB) *scheduler.Scheduler {
+	cli, err := handlers.NewDockerClient()
+	if err != nil {
+		log.Fatalf("Failed to create scheduler docker client: %v", err)
+	}
+
+	sched := scheduler.New(db, cli)
+	if err := sched.Start(); err != nil {
+		log.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	return sched
+}
+
+func startUpdateChecker(db *gorm.DB) *updates.Checker {
+	cfg := config.Get()
+	cli, err := handlers.NewDockerClient()
+	if err != nil {
+		log.Fatalf("Failed to create update checker docker client: %v", err)
+	}
+
+	checker := updates.New(cli, registry.NewClient(cfg.RegistryURL, registry.NewCredentials(db)), cfg.UpdateCheckInterval)
+	checker.Start()
+
+	return checker
+}
+
 func configureCors(r *gin.Engine) {
 	cfg := config.Get()
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{cfg.AllowOrigin},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Cache-Control", "Connection", "Transfer-Encoding"},
+		AllowMethods:     []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Cache-Control", "Connection", "Transfer-Encoding", "Upload-Offset"},
+		ExposeHeaders:    []string{"Location", "Upload-Offset", "Upload-Length"},
 		AllowCredentials: true,
 	}))
 }
@@ -61,9 +150,29 @@ func initializeDatabase() *gorm.DB {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Schedules were keyed by container ID before they were keyed by name, the scheduler converts the values
+	if db.Migrator().HasColumn(&models.Schedule{}, "container_id") {
+		if err := db.Migrator().RenameColumn(&models.Schedule{}, "container_id", "container"); err != nil {
+			log.Fatalf("Failed to migrate schedules: %v", err)
+		}
+	}
+
+	migrateRconPasswords(db)
+
 	if err := db.AutoMigrate(
 		&models.User{},
 		&models.AllowedUser{},
+		&models.ContainerTemplate{},
+		&models.Schedule{},
+		&models.BackupPolicy{},
+		&models.RconSettings{},
+		&models.QuerySettings{},
+		&models.AuditEntry{},
+		&models.ContainerPermission{},
+		&models.APIToken{},
+		&models.Session{},
+		&models.RegistryCredential{},
+		&models.FileUpload{},
 	); err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
@@ -71,26 +180,64 @@ func initializeDatabase() *gorm.DB {
 	return db
 }
 
+// migrateRconPasswords encrypts RCON passwords stored in plaintext by earlier versions. Without an encryption key
+// they are cleared instead and have to be entered again once ENCRYPTION_KEY is set.
+func migrateRconPasswords(db *gorm.DB) {
+	if !db.Migrator().HasColumn(&models.RconSettings{}, "password") {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().RenameColumn(&models.RconSettings{}, "password", "password_encrypted"); err != nil {
+			return err
+		}
+
+		var settings []models.RconSettings
+		if err := tx.Find(&settings).Error; err != nil {
+			return err
+		}
+		for _, s := range settings {
+			encrypted, err := secrets.Encrypt(s.PasswordEncrypted)
+			if errors.Is(err, secrets.ErrNoKey) {
+				log.Printf("Warning: cleared the RCON password of %s, set ENCRYPTION_KEY and enter it again", s.Container)
+				encrypted = ""
+			} else if err != nil {
+				return err
+			}
+			if err := tx.Model(&s).UpdateColumn("password_encrypted", encrypted).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to encrypt RCON passwords: %v", err)
+	}
+}
+
 func setGinMode() {
 	if strings.ToLower(config.Get().AppEnv) == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 }
 
-func registerRoutes(r *gin.Engine, db *gorm.DB) {
+func registerRoutes(r *gin.Engine, db *gorm.DB, sched *scheduler.Scheduler, checker *updates.Checker) {
 	// Register Auth handlers
-	authHandler := handlers.NewAuthHandler(db)
+	authHandler, err := handlers.NewAuthHandler(db)
+	if err != nil {
+		log.Fatalf("Failed to create auth handler: %v", err)
+	}
 	authHandler.RegisterAuthHandlers(r.Group("/auth"))
 
 	// Register Docker handlers
-	dockerHandler, err := handlers.NewDockerHandler()
+	dockerHandler, err := handlers.NewDockerHandler(db, checker)
 	if err != nil {
 		log.Fatalf("Failed to create docker handler: %v", err)
 	}
 	dockerHandler.RegisterDockerHandlers(r.Group("/docker"))
 
 	// Register File handlers
-	fileHandler, err := handlers.NewFileHandler()
+	fileHandler, err := handlers.NewFileHandler(db)
 	if err != nil {
 		log.Fatalf("Failed to create file handler: %v", err)
 	}
@@ -102,6 +249,35 @@ func registerRoutes(r *gin.Engine, db *gorm.DB) {
 	// User Management Routes
 	usersHandler := handlers.NewUsersHandler(db)
 	usersHandler.RegisterUsersRoutes(r.Group("/users"))
+
+	// Register Template handlers
+	templatesHandler, err := handlers.NewTemplatesHandler(db)
+	if err != nil {
+		log.Fatalf("Failed to create templates handler: %v", err)
+	}
+	templatesHandler.RegisterTemplatesRoutes(r.Group("/templates"))
+
+	// Register Schedule handlers
+	schedulesHandler, err := handlers.NewSchedulesHandler(db, sched)
+	if err != nil {
+		log.Fatalf("Failed to create schedules handler: %v", err)
+	}
+	schedulesHandler.RegisterSchedulesRoutes(r.Group("/schedules"))
+
+	// Register Backup handlers
+	backupsHandler, err := handlers.NewBackupsHandler(db)
+	if err != nil {
+		log.Fatalf("Failed to create backups handler: %v", err)
+	}
+	backupsHandler.RegisterBackupsRoutes(r.Group("/backups"))
+
+	// Register Audit handlers
+	auditHandler := handlers.NewAuditHandler(db)
+	auditHandler.RegisterAuditRoutes(r.Group("/audit"))
+
+	// Register API token handlers
+	tokensHandler := handlers.NewTokensHandler(db)
+	tokensHandler.RegisterTokensRoutes(r.Group("/tokens"))
 }
 
 func startServer(r *gin.Engine) {
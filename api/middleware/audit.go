@@ -0,0 +1,204 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gsm/models"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	AUDIT_MAX_BODY    = 64 * 1024 // Largest request body parsed for the summary
+	AUDIT_MAX_SUMMARY = 1024      // Longest summary stored per entry
+	AUDIT_MAX_ERROR   = 512       // Longest error response stored per entry
+)
+
+// Keys whose values never end up in the audit log
+var auditRedactedKeys = []string{"password", "secret", "token", "content"}
+
+// auditWriter keeps the beginning of the response so errors can be recorded
+type auditWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditWriter) Write(p []byte) (int, error) {
+	if remaining := AUDIT_MAX_ERROR - w.body.Len(); remaining > 0 {
+		if len(p) < remaining {
+			remaining = len(p)
+		}
+		w.body.Write(p[:remaining])
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *auditWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Audit records every POST, PUT, PATCH and DELETE request once it has been handled
+func Audit(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+
+		summary := auditRequestSummary(c)
+
+		writer := &auditWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		action := c.FullPath()
+		if action == "" {
+			action = c.Request.URL.Path
+		}
+
+		entry := models.AuditEntry{
+			Actor:    c.GetString("userEmail"),
+			Role:     models.UserRole(c.GetString("userRole")),
+			Method:   c.Request.Method,
+			Action:   action,
+			Target:   auditTarget(c),
+			Summary:  summary,
+			Status:   writer.Status(),
+			Outcome:  models.AuditOutcomeSuccess,
+			ClientIP: c.ClientIP(),
+		}
+
+		// Multipart bodies are parsed by the handler, record the uploaded file names
+		if c.Request.MultipartForm != nil {
+			entry.Summary = auditMultipartSummary(c)
+		}
+
+		if entry.Status >= http.StatusBadRequest {
+			entry.Outcome = models.AuditOutcomeFailure
+			entry.Error = auditError(writer.body.Bytes())
+		}
+
+		if err := db.Create(&entry).Error; err != nil {
+			log.Printf("Failed to write audit entry for %s %s: %v", entry.Method, entry.Action, err)
+		}
+	}
+}
+
+// auditRequestSummary reads the start of a JSON body and puts it back for the handler
+func auditRequestSummary(c *gin.Context) string {
+	if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), "application/json") {
+		return ""
+	}
+
+	prefix, err := io.ReadAll(io.LimitReader(c.Request.Body, AUDIT_MAX_BODY+1))
+	c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(prefix), c.Request.Body), c.Request.Body}
+	if err != nil || len(prefix) == 0 {
+		return ""
+	}
+	if len(prefix) > AUDIT_MAX_BODY {
+		return "(body too large)"
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(prefix, &body); err != nil {
+		return "(invalid json)"
+	}
+
+	summary, err := json.Marshal(redact(body))
+	if err != nil {
+		return ""
+	}
+	return truncate(string(summary), AUDIT_MAX_SUMMARY)
+}
+
+func auditMultipartSummary(c *gin.Context) string {
+	var parts []string
+	for field, values := range c.Request.MultipartForm.Value {
+		parts = append(parts, fmt.Sprintf("%s=%s", field, strings.Join(values, ",")))
+	}
+	for field, headers := range c.Request.MultipartForm.File {
+		for _, header := range headers {
+			parts = append(parts, fmt.Sprintf("%s=%s (%d bytes)", field, header.Filename, header.Size))
+		}
+	}
+	sort.Strings(parts)
+	return truncate(strings.Join(parts, ", "), AUDIT_MAX_SUMMARY)
+}
+
+// auditTarget describes what the request acted on from its route parameters and path query
+func auditTarget(c *gin.Context) string {
+	var parts []string
+	for _, param := range c.Params {
+		parts = append(parts, fmt.Sprintf("%s=%s", param.Key, param.Value))
+	}
+	if path := c.Query("path"); path != "" {
+		parts = append(parts, fmt.Sprintf("path=%s", path))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// auditError extracts the "error" field of a JSON error response
+func auditError(body []byte) string {
+	var response struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err == nil && response.Error != "" {
+		return truncate(response.Error, AUDIT_MAX_ERROR)
+	}
+	return truncate(string(body), AUDIT_MAX_ERROR)
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if isRedactedKey(key) {
+				v[key] = "[redacted]"
+				continue
+			}
+			v[key] = redact(nested)
+		}
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = redact(nested)
+		}
+	case string:
+		// Environment variables, e.g. "RCON_PASSWORD=..."
+		if key, _, found := strings.Cut(v, "="); found && isRedactedKey(key) {
+			return key + "=[redacted]"
+		}
+	}
+	return value
+}
+
+func isRedactedKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, redacted := range auditRedactedKeys {
+		if strings.Contains(key, redacted) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
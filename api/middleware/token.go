@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"fmt"
+	"gsm/models"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(c *gin.Context) (string, bool) {
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// checkAPIToken resolves the token to its owner and stores the user and the token's scopes in the context.
+// The role is read from the allowed users so removed or demoted users lose access immediately.
+func checkAPIToken(c *gin.Context, db *gorm.DB, tokenString string) {
+	if tokenString == "" {
+		return
+	}
+
+	// Find instead of First, unknown tokens are expected and should not be logged as errors
+	var token models.APIToken
+	result := db.Where("hash = ? AND (expires_at IS NULL OR expires_at > ?)", models.HashToken(tokenString), time.Now()).
+		Limit(1).Find(&token)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	var allowedUser models.AllowedUser
+	if err := db.Where("email = ?", token.Email).First(&allowedUser).Error; err != nil {
+		return
+	}
+
+	var user models.User
+	if err := db.Where("email = ?", token.Email).First(&user).Error; err != nil {
+		return
+	}
+
+	if err := db.Model(&token).UpdateColumn("last_used_at", time.Now()).Error; err != nil {
+		log.Printf("Failed to update last use of token %d: %v", token.ID, err)
+	}
+
+	c.Set("userID", fmt.Sprint(user.ID))
+	c.Set("userEmail", user.Email)
+	c.Set("userRole", string(allowedUser.Role))
+	c.Set("userPicture", user.Picture)
+	c.Set("tokenID", token.ID)
+	c.Set("tokenScopes", token.Scopes)
+}
+
+// TokenAllows reports whether the request may use the permission, requests not made with an API token are not limited
+func TokenAllows(c *gin.Context, permission models.Permission) bool {
+	value, exists := c.Get("tokenScopes")
+	if !exists {
+		return true
+	}
+	return slices.Contains(value.([]models.Permission), permission)
+}
+
+// RequireScope aborts requests made with an API token lacking the permission.
+// It is used on routes open to every user where access is decided per container.
+func RequireScope(permission models.Permission) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !TokenAllows(c, permission) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
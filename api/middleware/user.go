@@ -3,13 +3,30 @@ package middlewares
 import (
 	"fmt"
 	"gsm/config"
-	"strings"
+	"gsm/models"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
 )
 
-func CheckUser(c *gin.Context) {
+// CheckUser stores the user of the request's API token or session cookie in the context, db is used to
+// look up API tokens and check that sessions have not been revoked
+func CheckUser(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		checkUser(c, db)
+	}
+}
+
+func checkUser(c *gin.Context, db *gorm.DB) {
+	// API tokens take precedence over the session cookie
+	if tokenString, ok := bearerToken(c); ok {
+		checkAPIToken(c, db, tokenString)
+		c.Next()
+		return
+	}
+
 	cfg := config.Get()
 	tokenString, err := c.Cookie("token")
 	if err != nil {
@@ -31,8 +48,8 @@ func CheckUser(c *gin.Context) {
 		return
 	}
 
-	// If token is valid, store claims in context
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+	// If token is valid and its session has not been revoked, store claims in context
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid && sessionActive(db, claims) {
 		if id, exists := claims["id"].(string); exists {
 			c.Set("userID", id)
 		}
@@ -50,12 +67,27 @@ func CheckUser(c *gin.Context) {
 	c.Next()
 }
 
-func RequireRole(role string) func(c *gin.Context) {
+// sessionActive reports whether the session the access token was issued for is still active
+func sessionActive(db *gorm.DB, claims jwt.MapClaims) bool {
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return false
+	}
+
+	var count int64
+	err := db.Model(&models.Session{}).
+		Where("jti = ? AND revoked_at IS NULL AND expires_at > ?", jti, time.Now()).
+		Count(&count).Error
+	return err == nil && count > 0
+}
+
+// RequirePermission aborts the request unless the user's role grants the permission
+func RequirePermission(permission models.Permission) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		userRole := c.GetString("userRole")
+		userRole := models.UserRole(c.GetString("userRole"))
 
-		if !strings.EqualFold(userRole, role) {
-			c.JSON(401, gin.H{"error": "Unauthorized"})
+		if !userRole.Can(permission) || !TokenAllows(c, permission) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
 			c.Abort()
 			return
 		}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type AuditOutcome string
+
+const (
+	AuditOutcomeSuccess AuditOutcome = "success"
+	AuditOutcomeFailure AuditOutcome = "failure"
+)
+
+// AuditEntry records a single mutating request, entries are never updated or soft deleted
+type AuditEntry struct {
+	ID        uint         `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time    `gorm:"index" json:"createdAt"`
+	Actor     string       `gorm:"index" json:"actor"`   // Email of the user, empty for unauthenticated requests
+	Role      UserRole     `json:"role"`                 // Role the user had at the time of the request
+	Method    string       `json:"method"`               // HTTP method
+	Action    string       `gorm:"index" json:"action"`  // Route pattern, e.g. "/docker/containers/:id/stop"
+	Target    string       `gorm:"index" json:"target"`  // Route parameters and path the action was applied to
+	Summary   string       `json:"summary"`              // Truncated request body with secrets redacted
+	Status    int          `json:"status"`               // HTTP response status
+	Outcome   AuditOutcome `gorm:"index" json:"outcome"` // Derived from the status
+	Error     string       `json:"error,omitempty"`      // Error returned to the client on failure
+	ClientIP  string       `json:"clientIp"`
+}
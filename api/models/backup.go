@@ -0,0 +1,9 @@
+package models
+
+import "gorm.io/gorm"
+
+type BackupPolicy struct {
+	gorm.Model
+	Container string `gorm:"uniqueIndex;not null" json:"container"` // Container name the policy applies to
+	Retain    int    `gorm:"not null" json:"retain"`                // Number of snapshots to keep, 0 keeps all
+}
@@ -0,0 +1,35 @@
+package models
+
+import "gorm.io/gorm"
+
+type ContainerAction string
+
+const (
+	ContainerActionView    ContainerAction = "view"    // See the container, its logs and stats
+	ContainerActionControl ContainerAction = "control" // Start, stop and restart
+	ContainerActionConsole ContainerAction = "console" // Exec and RCON, attach needs the global containers.attach permission
+	ContainerActionFiles   ContainerAction = "files"   // Browse and edit the container's volume files
+	ContainerActionEdit    ContainerAction = "edit"    // Change the container configuration
+)
+
+var ContainerActions = []ContainerAction{
+	ContainerActionView,
+	ContainerActionControl,
+	ContainerActionConsole,
+	ContainerActionFiles,
+	ContainerActionEdit,
+}
+
+// Permission returns the global permission that allows the action on every container
+func (a ContainerAction) Permission() Permission {
+	return Permission("containers." + string(a))
+}
+
+// ContainerPermission grants a single action on a container to a user
+type ContainerPermission struct {
+	gorm.Model
+	Container string          `gorm:"uniqueIndex:idx_container_permission;not null" json:"container"` // Container name, stable across recreation
+	Email     string          `gorm:"uniqueIndex:idx_container_permission;not null" json:"email"`     // Email of the user the action is granted to
+	Action    ContainerAction `gorm:"uniqueIndex:idx_container_permission;not null" json:"action"`
+	GrantedBy string          `json:"grantedBy"` // Email of the admin who granted the action
+}
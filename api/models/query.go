@@ -0,0 +1,10 @@
+package models
+
+import "gorm.io/gorm"
+
+type QuerySettings struct {
+	gorm.Model
+	Container string `gorm:"uniqueIndex;not null" json:"container"` // Container name the settings apply to
+	Protocol  string `gorm:"not null" json:"protocol"`              // Query protocol, see query.Protocol
+	Port      uint16 `gorm:"not null" json:"port"`                  // Container side query port, resolved to the host port on use
+}
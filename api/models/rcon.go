@@ -0,0 +1,10 @@
+package models
+
+import "gorm.io/gorm"
+
+type RconSettings struct {
+	gorm.Model
+	Container         string `gorm:"uniqueIndex;not null" json:"container"` // Container name the settings apply to
+	Port              uint16 `gorm:"not null" json:"port"`                  // Container side RCON port, resolved to the host port on use
+	PasswordEncrypted string `gorm:"not null" json:"-"`                     // Encrypted with the secrets package, never returned to clients
+}
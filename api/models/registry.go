@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+// RegistryCredential is used for pulls from images on Host, the password is encrypted with gsm/secrets
+type RegistryCredential struct {
+	gorm.Model
+	Host              string `gorm:"uniqueIndex;not null" json:"host"` // Registry host as in image names, e.g. "ghcr.io" or "docker.io"
+	Username          string `gorm:"not null" json:"username"`
+	PasswordEncrypted string `gorm:"not null" json:"-"`
+	CreatedBy         string `json:"createdBy"` // Email of the admin who stored the credential
+}
@@ -0,0 +1,102 @@
+package models
+
+import "strings"
+
+type Permission string
+
+const (
+	PermissionContainersView    Permission = "containers.view"
+	PermissionContainersControl Permission = "containers.control"
+	PermissionContainersConsole Permission = "containers.console"
+	PermissionContainersAttach  Permission = "containers.attach"
+	PermissionContainersFiles   Permission = "containers.files"
+	PermissionContainersEdit    Permission = "containers.edit"
+	PermissionContainersCreate  Permission = "containers.create"
+	PermissionContainersDelete  Permission = "containers.delete"
+	PermissionImagesView        Permission = "images.view"
+	PermissionImagesPull        Permission = "images.pull"
+	PermissionImagesDelete      Permission = "images.delete"
+	PermissionRegistriesManage  Permission = "registries.manage"
+	PermissionSchedulesManage   Permission = "schedules.manage"
+	PermissionBackupsManage     Permission = "backups.manage"
+	PermissionTemplatesManage   Permission = "templates.manage"
+	PermissionPermissionsManage Permission = "permissions.manage"
+	PermissionUsersManage       Permission = "users.manage"
+	PermissionAuditView         Permission = "audit.view"
+)
+
+// Roles from least to most privileged, every role inherits the permissions of the roles before it
+var Roles = []UserRole{UserRoleDefault, UserRoleMod, UserRoleAdmin}
+
+// rolePermissions holds the permissions each role adds on top of the roles below it.
+// Plain users get no global permissions, they rely on per-container grants.
+var rolePermissions = map[UserRole][]Permission{
+	UserRoleDefault: {},
+	UserRoleMod: {
+		PermissionContainersView,
+		PermissionContainersControl,
+		PermissionContainersConsole,
+		PermissionContainersFiles,
+		PermissionImagesView,
+		PermissionImagesPull,
+		PermissionSchedulesManage,
+		PermissionBackupsManage,
+	},
+	// Editing sets images, volumes and ports, which is as powerful as creating containers.
+	// Attaching types into the server's own stdin and stays admin only, consoles use exec and RCON.
+	UserRoleAdmin: {
+		PermissionContainersEdit,
+		PermissionContainersAttach,
+		PermissionContainersCreate,
+		PermissionContainersDelete,
+		PermissionImagesDelete,
+		PermissionRegistriesManage,
+		PermissionTemplatesManage,
+		PermissionPermissionsManage,
+		PermissionUsersManage,
+		PermissionAuditView,
+	},
+}
+
+// ParseUserRole normalizes a role name, ok is false for unknown roles
+func ParseUserRole(role string) (UserRole, bool) {
+	r := UserRole(strings.ToLower(role))
+	for _, known := range Roles {
+		if r == known {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+// Can reports whether the role or any role below it has the permission
+func (r UserRole) Can(permission Permission) bool {
+	for _, p := range r.Permissions() {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns every permission of the role including inherited ones
+func (r UserRole) Permissions() []Permission {
+	role, ok := ParseUserRole(string(r))
+	if !ok {
+		return []Permission{}
+	}
+
+	permissions := []Permission{}
+	for _, inherited := range Roles {
+		permissions = append(permissions, rolePermissions[inherited]...)
+		if inherited == role {
+			break
+		}
+	}
+	return permissions
+}
+
+// IsValidPermission reports whether the permission is part of the matrix
+func IsValidPermission(permission Permission) bool {
+	return UserRoleAdmin.Can(permission)
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ScheduleAction string
+
+const (
+	ScheduleActionStart   ScheduleAction = "start"
+	ScheduleActionStop    ScheduleAction = "stop"
+	ScheduleActionRestart ScheduleAction = "restart"
+)
+
+type ScheduleStatus string
+
+const (
+	ScheduleStatusSuccess ScheduleStatus = "success"
+	ScheduleStatusFailed  ScheduleStatus = "failed"
+)
+
+type Schedule struct {
+	gorm.Model
+	Name       string         `json:"name"`                            // Optional label shown in the UI
+	Container  string         `gorm:"index;not null" json:"container"` // Name of the container the action is fired against, stable across recreation
+	Action     ScheduleAction `gorm:"not null" json:"action"`          // Action to perform on the container
+	Spec       string         `gorm:"not null" json:"spec"`            // Cron expression, e.g. "0 4 * * *" or "CRON_TZ=Europe/Warsaw 0 4 * * 1-5"
+	Enabled    bool           `gorm:"not null" json:"enabled"`         // Disabled schedules are kept but never fired
+	CreatedBy  string         `json:"createdBy"`                       // Email of the user who created the schedule
+	LastRunAt  *time.Time     `json:"lastRunAt"`                       // Time of the last run, nil if never run
+	LastStatus ScheduleStatus `json:"lastStatus"`                      // Result of the last run
+	LastError  string         `json:"lastError"`                       // Error message of the last failed run
+	NextRunAt  *time.Time     `gorm:"-" json:"nextRunAt"`              // Computed by the scheduler, not persisted
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Session is a sign-in, access tokens carry its JTI and are only accepted while it is active
+type Session struct {
+	ID                  uint       `gorm:"primarykey" json:"id"`
+	JTI                 string     `gorm:"uniqueIndex;not null" json:"jti"`
+	Email               string     `gorm:"index;not null" json:"email"`
+	RefreshHash         string     `gorm:"uniqueIndex;not null" json:"-"` // SHA-256 of the current refresh token
+	PreviousRefreshHash string     `gorm:"index" json:"-"`                // SHA-256 of the rotated refresh token, used to detect reuse
+	IssuedAt            time.Time  `json:"issuedAt"`
+	RefreshedAt         *time.Time `json:"refreshedAt"`
+	ExpiresAt           time.Time  `gorm:"index" json:"expiresAt"` // Refresh tokens are rejected after this
+	RevokedAt           *time.Time `json:"revokedAt"`
+	RevokedReason       string     `json:"revokedReason,omitempty"`
+	UserAgent           string     `json:"userAgent"`
+	ClientIP            string     `json:"clientIp"`
+}
+
+// Active reports whether the session can still be used
+func (s *Session) Active() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"gsm/docker"
+
+	"gorm.io/gorm"
+)
+
+type ContainerTemplate struct {
+	gorm.Model
+	Name        string                 `gorm:"uniqueIndex;not null" json:"name"`       // Ensures template name is unique and not null
+	Description string                 `json:"description"`                            // Optional human readable description
+	Config      docker.ContainerCreate `gorm:"serializer:json;not null" json:"config"` // Full container create payload
+	CreatedBy   string                 `json:"createdBy"`                              // Email of the user who created the template
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	API_TOKEN_PREFIX     = "gsm_" // Makes tokens recognizable, e.g. for secret scanners
+	REFRESH_TOKEN_PREFIX = "gsmr_"
+	TOKEN_BYTES          = 32
+)
+
+// APIToken is a personal access token, only its hash is stored
+type APIToken struct {
+	gorm.Model
+	Name       string       `gorm:"not null" json:"name"`
+	Email      string       `gorm:"index;not null" json:"email"`            // Email of the user the token acts as
+	Hash       string       `gorm:"uniqueIndex;not null" json:"-"`          // SHA-256 of the token
+	Hint       string       `json:"hint"`                                   // Last characters of the token to tell tokens apart
+	Scopes     []Permission `gorm:"serializer:json;not null" json:"scopes"` // Permissions the token is limited to
+	ExpiresAt  *time.Time   `json:"expiresAt"`                              // Nil for tokens that never expire
+	LastUsedAt *time.Time   `json:"lastUsedAt"`
+}
+
+// NewTokenSecret generates a random token with the prefix and returns it along with its hash
+func NewTokenSecret(prefix string) (string, string, error) {
+	b := make([]byte, TOKEN_BYTES)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	token := prefix + hex.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken hashes a token for lookup, tokens are random so a fast hash is enough
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// FileUpload is a resumable upload in progress, its data is staged outside the volumes until it is finalized
+type FileUpload struct {
+	ID        string    `gorm:"primarykey" json:"id"`
+	Email     string    `gorm:"index;not null" json:"email"` // Owner, the only user who may continue the upload
+	Path      string    `gorm:"not null" json:"path"`        // Destination directory
+	Filename  string    `gorm:"not null" json:"filename"`
+	Size      int64     `json:"size"` // Total size in bytes, declared when the upload is created
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `gorm:"index" json:"updatedAt"` // Last received chunk, stale uploads are removed
+}
@@ -22,6 +22,8 @@ type User struct {
 
 type AllowedUser struct {
 	gorm.Model
-	Email string   `gorm:"uniqueIndex;not null" json:"email"` // Ensures email is unique and not null
-	Role  UserRole `gorm:"not null" json:"role"`              // Ensures role is not null
+	Email        string   `gorm:"uniqueIndex;not null" json:"email"` // Ensures email is unique and not null
+	Role         UserRole `gorm:"not null" json:"role"`              // Ensures role is not null
+	PasswordHash string   `json:"-"`                                 // Bcrypt hash for the local provider, empty if unset
+	ManagedBy    string   `json:"managedBy"`                         // Provider that sets the role at every sign in, empty for users managed by hand
 }
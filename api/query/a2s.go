@@ -0,0 +1,155 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	a2sInfoRequest  = 0x54 // 'T'
+	a2sInfoResponse = 0x49 // 'I'
+	a2sChallenge    = 0x41 // 'A'
+	a2sMaxPacket    = 1400
+	a2sTheShipAppID = 2400
+)
+
+var (
+	a2sSimpleHeader = []byte{0xFF, 0xFF, 0xFF, 0xFF}
+	a2sSplitHeader  = []byte{0xFE, 0xFF, 0xFF, 0xFF}
+	a2sInfoPayload  = []byte("Source Engine Query\x00")
+)
+
+// queryA2S sends an A2S_INFO request, answering the challenge newer servers send first
+func queryA2S(ctx context.Context, address string) (*Info, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", address, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	request := append(append(append([]byte{}, a2sSimpleHeader...), a2sInfoRequest), a2sInfoPayload...)
+	response, err := a2sExchange(conn, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response[0] == a2sChallenge {
+		if len(response) < 5 {
+			return nil, errors.New("invalid a2s challenge response")
+		}
+		response, err = a2sExchange(conn, append(request, response[1:5]...))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if response[0] != a2sInfoResponse {
+		return nil, fmt.Errorf("unexpected a2s response type 0x%x", response[0])
+	}
+
+	return parseA2SInfo(response[1:])
+}
+
+// a2sExchange sends a request and returns the response payload without the packet header
+func a2sExchange(conn net.Conn, request []byte) ([]byte, error) {
+	if _, err := conn.Write(request); err != nil {
+		return nil, fmt.Errorf("failed to send a2s request: %v", err)
+	}
+
+	buf := make([]byte, a2sMaxPacket)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read a2s response: %v", err)
+	}
+
+	if n >= 4 && bytes.Equal(buf[:4], a2sSplitHeader) {
+		return nil, errors.New("split a2s responses are not supported")
+	}
+
+	if n < 5 || !bytes.Equal(buf[:4], a2sSimpleHeader) {
+		return nil, errors.New("invalid a2s response")
+	}
+
+	return buf[4:n], nil
+}
+
+func parseA2SInfo(data []byte) (*Info, error) {
+	reader := bytes.NewReader(data)
+
+	// Protocol version
+	if _, err := reader.ReadByte(); err != nil {
+		return nil, errors.New("truncated a2s info response")
+	}
+
+	name, err := readCString(reader)
+	if err != nil {
+		return nil, err
+	}
+	mapName, err := readCString(reader)
+	if err != nil {
+		return nil, err
+	}
+	// Folder and game
+	for i := 0; i < 2; i++ {
+		if _, err := readCString(reader); err != nil {
+			return nil, err
+		}
+	}
+
+	var header struct {
+		AppID      uint16
+		Players    uint8
+		MaxPlayers uint8
+		Bots       uint8
+		ServerType uint8
+		Env        uint8
+		Visibility uint8
+		VAC        uint8
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return nil, errors.New("truncated a2s info response")
+	}
+
+	// The Ship sends three extra bytes before the version
+	if header.AppID == a2sTheShipAppID {
+		if _, err := reader.Seek(3, 1); err != nil {
+			return nil, errors.New("truncated a2s info response")
+		}
+	}
+
+	version, err := readCString(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Info{
+		Name:       name,
+		Map:        mapName,
+		Version:    version,
+		Players:    int(header.Players),
+		MaxPlayers: int(header.MaxPlayers),
+	}, nil
+}
+
+func readCString(reader *bytes.Reader) (string, error) {
+	var value []byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return "", errors.New("truncated a2s info response")
+		}
+		if b == 0 {
+			return string(value), nil
+		}
+		value = append(value, b)
+	}
+}
@@ -0,0 +1,159 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+const (
+	minecraftMaxResponse     = 1 << 20 // Status JSON including favicon stays well below this
+	minecraftProtocolVersion = -1      // Servers answer status requests for any version
+	minecraftStatusState     = 1
+)
+
+type minecraftStatus struct {
+	Version struct {
+		Name string `json:"name"`
+	} `json:"version"`
+	Players struct {
+		Max    int `json:"max"`
+		Online int `json:"online"`
+	} `json:"players"`
+	Description json.RawMessage `json:"description"`
+}
+
+// queryMinecraft performs a Server List Ping handshake and status request
+func queryMinecraft(ctx context.Context, address string) (*Info, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %s: %v", address, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %s: %v", portStr, err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", address, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Handshake: protocol version, server address, port, next state
+	var handshake bytes.Buffer
+	writeVarInt(&handshake, 0x00)
+	writeVarInt(&handshake, minecraftProtocolVersion)
+	writeVarInt(&handshake, int32(len(host)))
+	handshake.WriteString(host)
+	binary.Write(&handshake, binary.BigEndian, uint16(port))
+	writeVarInt(&handshake, minecraftStatusState)
+
+	var request bytes.Buffer
+	writeVarInt(&request, int32(handshake.Len()))
+	request.Write(handshake.Bytes())
+	// Status request: empty packet with id 0x00
+	writeVarInt(&request, 1)
+	writeVarInt(&request, 0x00)
+
+	if _, err := conn.Write(request.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to send status request: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	length, err := readVarInt(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status response: %v", err)
+	}
+	if length <= 0 || length > minecraftMaxResponse {
+		return nil, fmt.Errorf("invalid status response length: %d", length)
+	}
+
+	packet := bufio.NewReader(io.LimitReader(reader, int64(length)))
+	if packetID, err := readVarInt(packet); err != nil || packetID != 0x00 {
+		return nil, errors.New("invalid status response packet")
+	}
+
+	jsonLength, err := readVarInt(packet)
+	if err != nil || jsonLength <= 0 || jsonLength > length {
+		return nil, errors.New("invalid status response payload")
+	}
+
+	payload := make([]byte, jsonLength)
+	if _, err := io.ReadFull(packet, payload); err != nil {
+		return nil, fmt.Errorf("failed to read status response: %v", err)
+	}
+
+	var status minecraftStatus
+	if err := json.Unmarshal(payload, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse status response: %v", err)
+	}
+
+	return &Info{
+		Name:       minecraftDescription(status.Description),
+		Version:    status.Version.Name,
+		Players:    status.Players.Online,
+		MaxPlayers: status.Players.Max,
+	}, nil
+}
+
+// minecraftDescription flattens the MOTD, which is either a plain string or a chat component
+func minecraftDescription(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var component struct {
+		Text  string            `json:"text"`
+		Extra []json.RawMessage `json:"extra"`
+	}
+	if err := json.Unmarshal(raw, &component); err != nil {
+		return ""
+	}
+
+	text = component.Text
+	for _, extra := range component.Extra {
+		text += minecraftDescription(extra)
+	}
+	return text
+}
+
+func writeVarInt(buf *bytes.Buffer, value int32) {
+	v := uint32(value)
+	for {
+		if v&^0x7F == 0 {
+			buf.WriteByte(byte(v))
+			return
+		}
+		buf.WriteByte(byte(v&0x7F | 0x80))
+		v >>= 7
+	}
+}
+
+func readVarInt(reader io.ByteReader) (int32, error) {
+	var value uint32
+	for i := 0; i < 5; i++ {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return int32(value), nil
+		}
+	}
+	return 0, errors.New("varint too long")
+}
@@ -0,0 +1,62 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type Protocol string
+
+const (
+	ProtocolA2S       Protocol = "a2s"       // Steam server query, used by Source and most Steam games
+	ProtocolMinecraft Protocol = "minecraft" // Minecraft Server List Ping
+)
+
+const DEFAULT_TIMEOUT = 3 * time.Second
+
+type Info struct {
+	Protocol   Protocol `json:"protocol"`
+	Online     bool     `json:"online"`
+	Name       string   `json:"name"`
+	Map        string   `json:"map"`
+	Version    string   `json:"version"`
+	Players    int      `json:"players"`
+	MaxPlayers int      `json:"maxPlayers"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Network returns the transport the protocol runs over
+func (p Protocol) Network() string {
+	if p == ProtocolA2S {
+		return "udp"
+	}
+	return "tcp"
+}
+
+// Query asks the server at address for its status using the given protocol
+func Query(ctx context.Context, protocol Protocol, address string) (*Info, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DEFAULT_TIMEOUT)
+		defer cancel()
+	}
+
+	var info *Info
+	var err error
+	switch protocol {
+	case ProtocolA2S:
+		info, err = queryA2S(ctx, address)
+	case ProtocolMinecraft:
+		info, err = queryMinecraft(ctx, address)
+	default:
+		return nil, fmt.Errorf("unknown query protocol %q", protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info.Protocol = protocol
+	info.Online = true
+	return info, nil
+}
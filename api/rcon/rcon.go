@@ -0,0 +1,170 @@
+package rcon
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Packet types of the Source RCON protocol, Minecraft uses the same values
+const (
+	SERVERDATA_AUTH           int32 = 3
+	SERVERDATA_AUTH_RESPONSE  int32 = 2
+	SERVERDATA_EXECCOMMAND    int32 = 2
+	SERVERDATA_RESPONSE_VALUE int32 = 0
+)
+
+const (
+	DIAL_TIMEOUT    = 5 * time.Second
+	IO_TIMEOUT      = 10 * time.Second
+	MAX_COMMAND_LEN = 1446      // Largest command body accepted by both Source and Minecraft servers
+	MAX_PACKET_SIZE = 4096 + 10 // Largest body a server sends in one packet plus the header
+)
+
+const packetHeaderLen = 4 + 4 + 2 // id + type + two null terminators
+
+var ErrAuthFailed = errors.New("rcon authentication failed")
+
+type Client struct {
+	conn   net.Conn
+	nextID int32
+}
+
+type packet struct {
+	ID   int32
+	Type int32
+	Body string
+}
+
+// Dial connects to the RCON server at address and authenticates with password
+func Dial(ctx context.Context, address string, password string) (*Client, error) {
+	dialer := net.Dialer{Timeout: DIAL_TIMEOUT}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", address, err)
+	}
+
+	client := &Client{conn: conn, nextID: 1}
+
+	if err := client.authenticate(password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// Execute runs command on the server and returns its response
+func (c *Client) Execute(command string) (string, error) {
+	if len(command) > MAX_COMMAND_LEN {
+		return "", fmt.Errorf("command too long: %d bytes, max %d", len(command), MAX_COMMAND_LEN)
+	}
+
+	commandID := c.id()
+	if err := c.write(packet{ID: commandID, Type: SERVERDATA_EXECCOMMAND, Body: command}); err != nil {
+		return "", err
+	}
+
+	// Responses may be split across several packets. Servers answer requests in order,
+	// so an empty packet sent after the command marks the end of its response once echoed back.
+	terminatorID := c.id()
+	if err := c.write(packet{ID: terminatorID, Type: SERVERDATA_RESPONSE_VALUE}); err != nil {
+		return "", err
+	}
+
+	var response bytes.Buffer
+	for {
+		p, err := c.read()
+		if err != nil {
+			return "", err
+		}
+
+		switch p.ID {
+		case commandID:
+			response.WriteString(p.Body)
+		case terminatorID:
+			return response.String(), nil
+		}
+	}
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) authenticate(password string) error {
+	authID := c.id()
+	if err := c.write(packet{ID: authID, Type: SERVERDATA_AUTH, Body: password}); err != nil {
+		return err
+	}
+
+	// Source servers send an empty RESPONSE_VALUE before the auth response, Minecraft does not
+	for {
+		p, err := c.read()
+		if err != nil {
+			return err
+		}
+
+		if p.Type != SERVERDATA_AUTH_RESPONSE {
+			continue
+		}
+
+		// A wrong password is answered with id -1
+		if p.ID != authID {
+			return ErrAuthFailed
+		}
+
+		return nil
+	}
+}
+
+func (c *Client) id() int32 {
+	id := c.nextID
+	c.nextID++
+	return id
+}
+
+func (c *Client) write(p packet) error {
+	buf := bytes.NewBuffer(make([]byte, 0, 4+packetHeaderLen+len(p.Body)))
+	binary.Write(buf, binary.LittleEndian, int32(packetHeaderLen+len(p.Body)))
+	binary.Write(buf, binary.LittleEndian, p.ID)
+	binary.Write(buf, binary.LittleEndian, p.Type)
+	buf.WriteString(p.Body)
+	buf.Write([]byte{0, 0})
+
+	c.conn.SetWriteDeadline(time.Now().Add(IO_TIMEOUT))
+	if _, err := c.conn.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to send rcon packet: %v", err)
+	}
+
+	return nil
+}
+
+func (c *Client) read() (*packet, error) {
+	c.conn.SetReadDeadline(time.Now().Add(IO_TIMEOUT))
+
+	var size int32
+	if err := binary.Read(c.conn, binary.LittleEndian, &size); err != nil {
+		return nil, fmt.Errorf("failed to read rcon packet: %v", err)
+	}
+
+	if size < packetHeaderLen || size > MAX_PACKET_SIZE {
+		return nil, fmt.Errorf("invalid rcon packet size: %d", size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(c.conn, data); err != nil {
+		return nil, fmt.Errorf("failed to read rcon packet: %v", err)
+	}
+
+	return &packet{
+		ID:   int32(binary.LittleEndian.Uint32(data[0:4])),
+		Type: int32(binary.LittleEndian.Uint32(data[4:8])),
+		Body: string(bytes.TrimRight(data[8:], "\x00")),
+	}, nil
+}
@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"gsm/models"
+	"gsm/secrets"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+	"gorm.io/gorm"
+)
+
+// Credentials looks up stored registry credentials by the registry host of an image
+type Credentials struct {
+	db *gorm.DB
+}
+
+func NewCredentials(db *gorm.DB) *Credentials {
+	return &Credentials{db: db}
+}
+
+// ImageHost returns the registry host of an image name, "docker.io" for Docker Hub images
+func ImageHost(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image name %s: %v", image, err)
+	}
+	return reference.Domain(named), nil
+}
+
+// Has reports whether credentials are stored for host
+func (c *Credentials) Has(host string) (bool, error) {
+	var count int64
+	if err := c.db.Model(&models.RegistryCredential{}).Where("host = ?", host).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to fetch registry credential: %v", err)
+	}
+	return count > 0, nil
+}
+
+// Lookup returns the username and password stored for host, ok is false when there are none
+func (c *Credentials) Lookup(host string) (username, password string, ok bool, err error) {
+	var credential models.RegistryCredential
+	if err := c.db.Where("host = ?", host).First(&credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", "", false, nil
+		}
+		return "", "", false, fmt.Errorf("failed to fetch registry credential: %v", err)
+	}
+
+	password, err = secrets.Decrypt(credential.PasswordEncrypted)
+	if err != nil {
+		return "", "", false, fmt.Errorf("failed to read registry credential for %s: %v", host, err)
+	}
+	return credential.Username, password, true, nil
+}
+
+// PullAuth returns the encoded RegistryAuth for pulling the image, empty when no credential matches
+func (c *Credentials) PullAuth(image string) (string, error) {
+	host, err := ImageHost(image)
+	if err != nil {
+		return "", err
+	}
+
+	username, password, ok, err := c.Lookup(host)
+	if err != nil || !ok {
+		return "", err
+	}
+
+	return registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      username,
+		Password:      password,
+		ServerAddress: host,
+	})
+}
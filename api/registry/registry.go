@@ -0,0 +1,425 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distribution/reference"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	REQUEST_TIMEOUT   = 30 * time.Second
+	MANIFEST_WORKERS  = 5 // Concurrent manifest requests per tag page
+	DEFAULT_PAGE_SIZE = 50
+	MAX_PAGE_SIZE     = 200
+	MAX_RESPONSE_SIZE = 4 << 20
+	DOCKER_HUB_URL    = "https://registry-1.docker.io"
+
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+var (
+	ErrNotFound   = errors.New("repository not found")
+	ErrNotAllowed = errors.New("registry not allowed")
+)
+
+type Client interface {
+	// ListTags returns up to n tags of the image's repository following last
+	ListTags(ctx context.Context, image string, n int, last string) (*TagList, error)
+	// Digest returns the manifest digest the image's tag currently points at
+	Digest(ctx context.Context, image string) (string, error)
+}
+
+// registryClient talks to registries over the v2 HTTP API.
+// Images on Docker Hub are looked up on defaultURL, which can point at a local registry instead.
+// Other registries are only contacted when they are defaultURL's host or have stored credentials,
+// so image names cannot point requests at arbitrary hosts.
+type registryClient struct {
+	defaultURL  string
+	defaultHost string       // Host of defaultURL, images named with it are looked up there
+	credentials *Credentials // Optional, used for registries that require a login
+	http        *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]string // Bearer tokens by registry and scope
+}
+
+func NewClient(defaultURL string, credentials *Credentials) Client {
+	r := &registryClient{
+		defaultURL:  strings.TrimSuffix(defaultURL, "/"),
+		credentials: credentials,
+		http:        &http.Client{Timeout: REQUEST_TIMEOUT},
+		tokens:      make(map[string]string),
+	}
+	if u, err := url.Parse(r.defaultURL); err == nil {
+		r.defaultHost = u.Host
+	}
+	return r
+}
+
+// repo is a repository on a registry
+type repo struct {
+	baseURL string // e.g. "https://ghcr.io"
+	host    string // Host as in image names whose stored credentials belong to baseURL, empty for none
+	path    string // e.g. "library/nginx"
+}
+
+// resolve splits an image name into its registry and repository path
+func (r *registryClient) resolve(image string) (repo, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return repo{}, fmt.Errorf("invalid image name %s: %v", image, err)
+	}
+
+	domain := reference.Domain(named)
+	if domain == "docker.io" {
+		// Docker Hub credentials are not sent to a mirror configured as the default registry
+		if r.defaultURL != DOCKER_HUB_URL {
+			return repo{baseURL: r.defaultURL, path: reference.Path(named)}, nil
+		}
+		return repo{baseURL: r.defaultURL, host: domain, path: reference.Path(named)}, nil
+	}
+	if domain == r.defaultHost {
+		return repo{baseURL: r.defaultURL, host: domain, path: reference.Path(named)}, nil
+	}
+
+	allowed := false
+	if r.credentials != nil {
+		if allowed, err = r.credentials.Has(domain); err != nil {
+			return repo{}, err
+		}
+	}
+	if !allowed {
+		return repo{}, fmt.Errorf("%w: %s has no stored credentials", ErrNotAllowed, domain)
+	}
+	return repo{baseURL: "https://" + domain, host: domain, path: reference.Path(named)}, nil
+}
+
+func (r *registryClient) ListTags(ctx context.Context, image string, n int, last string) (*TagList, error) {
+	if n <= 0 {
+		n = DEFAULT_PAGE_SIZE
+	}
+	if n > MAX_PAGE_SIZE {
+		n = MAX_PAGE_SIZE
+	}
+
+	repository, err := r.resolve(image)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"n": {fmt.Sprint(n)}}
+	if last != "" {
+		query.Set("last", last)
+	}
+
+	var page struct {
+		Tags []string `json:"tags"`
+	}
+	resp, err := r.get(ctx, repository, fmt.Sprintf("/v2/%s/tags/list?%s", repository.path, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MAX_RESPONSE_SIZE)).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode tag list: %v", err)
+	}
+
+	tags := make([]Tag, len(page.Tags))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < MANIFEST_WORKERS; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				tags[i] = r.describeTag(ctx, repository, page.Tags[i])
+			}
+		}()
+	}
+	for i := range page.Tags {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	list := &TagList{Repository: repository.path, Tags: tags}
+	// A full page likely has more tags after it, the Link header is not set by every registry
+	if len(page.Tags) == n {
+		list.Next = page.Tags[len(page.Tags)-1]
+	}
+	return list, nil
+}
+
+func (r *registryClient) Digest(ctx context.Context, image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image name %s: %v", image, err)
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return "", fmt.Errorf("image %s is pinned to a digest", image)
+	}
+	tag := "latest"
+	if tagged, ok := named.(reference.Tagged); ok {
+		tag = tagged.Tag()
+	}
+
+	repository, err := r.resolve(image)
+	if err != nil {
+		return "", err
+	}
+
+	// HEAD requests are not counted against the Docker Hub pull rate limit
+	resp, err := r.do(ctx, http.MethodHead, repository, fmt.Sprintf("/v2/%s/manifests/%s", repository.path, tag), manifestHeader())
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry did not report a digest for %s", image)
+	}
+	return digest, nil
+}
+
+// describeTag looks up the digest and size of a tag, failures leave them empty rather than failing the listing
+func (r *registryClient) describeTag(ctx context.Context, repository repo, tag string) Tag {
+	result := Tag{Name: tag}
+
+	manifest, digest, err := r.manifest(ctx, repository, tag)
+	if err != nil {
+		return result
+	}
+	result.Digest = digest
+
+	// Multi-platform images report the size of the variant the host would pull
+	if len(manifest.Manifests) > 0 {
+		platform := platformManifest(manifest.Manifests)
+		if platform == nil {
+			return result
+		}
+		manifest, _, err = r.manifest(ctx, repository, platform.Digest.String())
+		if err != nil {
+			return result
+		}
+	}
+
+	if manifest.Config.Size > 0 {
+		result.Size = manifest.Config.Size
+	}
+	for _, layer := range manifest.Layers {
+		result.Size += layer.Size
+	}
+	return result
+}
+
+// manifestResponse covers both image manifests and indexes
+type manifestResponse struct {
+	Config    ocispec.Descriptor   `json:"config"`
+	Layers    []ocispec.Descriptor `json:"layers"`
+	Manifests []ocispec.Descriptor `json:"manifests"`
+}
+
+func (r *registryClient) manifest(ctx context.Context, repository repo, ref string) (*manifestResponse, string, error) {
+	resp, err := r.get(ctx, repository, fmt.Sprintf("/v2/%s/manifests/%s", repository.path, ref), manifestHeader())
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	var manifest manifestResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MAX_RESPONSE_SIZE)).Decode(&manifest); err != nil {
+		return nil, "", fmt.Errorf("failed to decode manifest: %v", err)
+	}
+	return &manifest, resp.Header.Get("Docker-Content-Digest"), nil
+}
+
+// manifestHeader accepts image manifests and indexes in both the OCI and Docker formats
+func manifestHeader() http.Header {
+	return http.Header{"Accept": {strings.Join([]string{
+		ocispec.MediaTypeImageIndex,
+		ocispec.MediaTypeImageManifest,
+		mediaTypeDockerManifestList,
+		mediaTypeDockerManifest,
+	}, ", ")}}
+}
+
+func platformManifest(manifests []ocispec.Descriptor) *ocispec.Descriptor {
+	for i, m := range manifests {
+		if m.Platform != nil && m.Platform.OS == runtime.GOOS && m.Platform.Architecture == runtime.GOARCH {
+			return &manifests[i]
+		}
+	}
+	return nil
+}
+
+func (r *registryClient) get(ctx context.Context, repository repo, path string, header http.Header) (*http.Response, error) {
+	return r.do(ctx, http.MethodGet, repository, path, header)
+}
+
+// do performs an authenticated request. Registries answer 401 with the authentication they expect,
+// either a bearer token from their token service or basic authentication with stored credentials.
+func (r *registryClient) do(ctx context.Context, method string, repository repo, path string, header http.Header) (*http.Response, error) {
+	scope := fmt.Sprintf("repository:%s:pull", repository.path)
+	tokenKey := repository.baseURL + " " + scope
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, repository.baseURL+path, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %v", err)
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		r.mu.Lock()
+		authorization := r.tokens[tokenKey]
+		r.mu.Unlock()
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+
+		resp, err := r.http.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reach registry: %v", err)
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return resp, nil
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0:
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			authorization, err := r.authorize(ctx, repository, challenge, scope)
+			if err != nil {
+				return nil, err
+			}
+			r.mu.Lock()
+			r.tokens[tokenKey] = authorization
+			r.mu.Unlock()
+		case resp.StatusCode == http.StatusNotFound:
+			resp.Body.Close()
+			return nil, ErrNotFound
+		default:
+			resp.Body.Close()
+			return nil, fmt.Errorf("registry responded with %s", resp.Status)
+		}
+	}
+}
+
+// authorize answers a WWW-Authenticate challenge with an Authorization header value
+func (r *registryClient) authorize(ctx context.Context, repository repo, challenge, scope string) (string, error) {
+	username, password, hasCredentials := "", "", false
+	if r.credentials != nil && repository.host != "" {
+		var err error
+		if username, password, hasCredentials, err = r.credentials.Lookup(repository.host); err != nil {
+			return "", err
+		}
+	}
+
+	scheme, params, _ := strings.Cut(challenge, " ")
+	switch {
+	case strings.EqualFold(scheme, "Bearer"):
+		token, err := r.fetchToken(ctx, repository, params, scope, username, password, hasCredentials)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	case strings.EqualFold(scheme, "Basic") && hasCredentials:
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil
+	default:
+		return "", fmt.Errorf("registry %s requires credentials", repository.baseURL)
+	}
+}
+
+// fetchToken requests a token from the realm of a `realm="...",service="..."` bearer challenge.
+// The realm is chosen by the registry, it has to use https unless it is on the registry itself.
+func (r *registryClient) fetchToken(ctx context.Context, repository repo, params, scope, username, password string, hasCredentials bool) (string, error) {
+	values := parseChallenge(params)
+	realm := values["realm"]
+	if realm == "" {
+		return "", errors.New("registry authentication challenge has no realm")
+	}
+	realmURL, err := url.Parse(realm)
+	if err != nil || realmURL.Host == "" || realmURL.User != nil {
+		return "", fmt.Errorf("invalid registry token realm %s", realm)
+	}
+	if realmURL.Scheme != "https" && realmURL.Scheme+"://"+realmURL.Host != repository.baseURL {
+		return "", fmt.Errorf("registry token realm %s does not use https", realm)
+	}
+
+	query := url.Values{"scope": {scope}}
+	if service := values["service"]; service != "" {
+		query.Set("service", service)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %v", err)
+	}
+	if hasCredentials {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request registry token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to request registry token: %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MAX_RESPONSE_SIZE)).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode registry token: %v", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// parseChallenge parses comma separated key="value" pairs
+func parseChallenge(params string) map[string]string {
+	values := make(map[string]string)
+	for params != "" {
+		var value string
+		// Values are quoted and may contain commas
+		key, rest, found := strings.Cut(params, "=")
+		if !found {
+			break
+		}
+		key = strings.TrimSpace(key)
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value = rest[1 : end+1]
+			params = strings.TrimPrefix(strings.TrimSpace(rest[end+2:]), ",")
+		} else {
+			value, params, _ = strings.Cut(rest, ",")
+		}
+		values[strings.ToLower(key)] = value
+	}
+	return values
+}
@@ -0,0 +1,15 @@
+package registry
+
+// Tag is an image tag with the digest pulled for it and its compressed size
+type Tag struct {
+	Name   string `json:"name"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"` // Size of the image for the host platform, 0 if unavailable
+}
+
+// TagList is a page of tags, Next is passed as last to fetch the following page
+type TagList struct {
+	Repository string `json:"repository"`
+	Tags       []Tag  `json:"tags"`
+	Next       string `json:"next,omitempty"`
+}
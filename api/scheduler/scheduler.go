@@ -0,0 +1,187 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"gsm/docker"
+	"gsm/models"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+const ACTION_TIMEOUT = 2 * time.Minute
+
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Scheduler fires docker actions for the schedules stored in the database
+type Scheduler struct {
+	db      *gorm.DB
+	cli     docker.Client
+	cron    *cron.Cron
+	mu      sync.Mutex
+	entries map[uint]cron.EntryID
+}
+
+func New(db *gorm.DB, cli docker.Client) *Scheduler {
+	return &Scheduler{
+		db:      db,
+		cli:     cli,
+		cron:    cron.New(cron.WithParser(parser)),
+		entries: make(map[uint]cron.EntryID),
+	}
+}
+
+// ValidateSpec checks that spec is a valid cron expression
+func ValidateSpec(spec string) error {
+	if _, err := parser.Parse(spec); err != nil {
+		return fmt.Errorf("invalid cron expression: %v", err)
+	}
+	return nil
+}
+
+// Start loads all enabled schedules and starts the background runner
+func (s *Scheduler) Start() error {
+	s.renameContainerIDs()
+
+	var schedules []models.Schedule
+	if err := s.db.Where("enabled = ?", true).Find(&schedules).Error; err != nil {
+		return fmt.Errorf("failed to load schedules: %v", err)
+	}
+
+	for _, schedule := range schedules {
+		if err := s.Register(schedule); err != nil {
+			log.Printf("Skipping schedule %d: %v", schedule.ID, err)
+		}
+	}
+
+	s.cron.Start()
+	return nil
+}
+
+// Stop stops the runner and waits for running actions to finish
+func (s *Scheduler) Stop() {
+	<-s.cron.Stop().Done()
+}
+
+// Register adds the schedule to the runner, replacing any previous entry for it
+func (s *Scheduler) Register(schedule models.Schedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.unregister(schedule.ID)
+
+	if !schedule.Enabled {
+		return nil
+	}
+
+	id := schedule.ID
+	entryID, err := s.cron.AddFunc(schedule.Spec, func() { s.Run(id) })
+	if err != nil {
+		return fmt.Errorf("invalid cron expression: %v", err)
+	}
+	s.entries[id] = entryID
+
+	return nil
+}
+
+// Unregister removes the schedule from the runner
+func (s *Scheduler) Unregister(id uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.unregister(id)
+}
+
+func (s *Scheduler) unregister(id uint) {
+	if entryID, ok := s.entries[id]; ok {
+		s.cron.Remove(entryID)
+		delete(s.entries, id)
+	}
+}
+
+// NextRun returns the next time the schedule fires, or nil if it is not registered
+func (s *Scheduler) NextRun(id uint) *time.Time {
+	s.mu.Lock()
+	entryID, ok := s.entries[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	next := s.cron.Entry(entryID).Next
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// Run executes the schedule's action immediately and records the result
+func (s *Scheduler) Run(id uint) error {
+	var schedule models.Schedule
+	if err := s.db.First(&schedule, id).Error; err != nil {
+		return fmt.Errorf("failed to load schedule %d: %v", id, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ACTION_TIMEOUT)
+	defer cancel()
+
+	runErr := s.execute(ctx, schedule)
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"last_run_at": &now,
+		"last_status": models.ScheduleStatusSuccess,
+		"last_error":  "",
+	}
+	if runErr != nil {
+		log.Printf("Schedule %d (%s %s) failed: %v", schedule.ID, schedule.Action, schedule.Container, runErr)
+		updates["last_status"] = models.ScheduleStatusFailed
+		updates["last_error"] = runErr.Error()
+	}
+
+	if err := s.db.Model(&models.Schedule{}).Where("id = ?", schedule.ID).Updates(updates).Error; err != nil {
+		log.Printf("Failed to record result of schedule %d: %v", schedule.ID, err)
+	}
+
+	return runErr
+}
+
+func (s *Scheduler) execute(ctx context.Context, schedule models.Schedule) error {
+	switch schedule.Action {
+	case models.ScheduleActionStart:
+		return s.cli.StartContainer(ctx, schedule.Container)
+	case models.ScheduleActionStop:
+		return s.cli.StopContainer(ctx, schedule.Container)
+	case models.ScheduleActionRestart:
+		return s.cli.RestartContainer(ctx, schedule.Container)
+	default:
+		return fmt.Errorf("unknown action %q", schedule.Action)
+	}
+}
+
+// renameContainerIDs replaces container IDs stored by earlier versions with the containers' names.
+// IDs change whenever a container is recreated, names do not. Names resolve to themselves.
+func (s *Scheduler) renameContainerIDs() {
+	var schedules []models.Schedule
+	if err := s.db.Find(&schedules).Error; err != nil {
+		log.Printf("Failed to load schedules: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ACTION_TIMEOUT)
+	defer cancel()
+
+	for _, schedule := range schedules {
+		name, err := s.cli.ContainerName(ctx, schedule.Container)
+		if err != nil || name == schedule.Container {
+			continue
+		}
+		if err := s.db.Model(&schedule).Update("container", name).Error; err != nil {
+			log.Printf("Failed to update container of schedule %d: %v", schedule.ID, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gsm/config"
+)
+
+// ErrNoKey is returned when neither ENCRYPTION_KEY nor a JWT_SECRET other than the default is set
+var ErrNoKey = errors.New("no encryption key is configured, set ENCRYPTION_KEY to store secrets")
+
+// key derives the AES-256 key from ENCRYPTION_KEY, changing it makes stored secrets unreadable
+func key() []byte {
+	sum := sha256.Sum256([]byte(config.Get().EncryptionKey))
+	return sum[:]
+}
+
+// Encrypt seals the plaintext with AES-GCM and returns the nonce and ciphertext as base64
+func Encrypt(plaintext string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value returned by Encrypt
+func Decrypt(encrypted string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret: %v", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("secret is too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+func newGCM() (cipher.AEAD, error) {
+	if config.Get().EncryptionKey == "" {
+		return nil, ErrNoKey
+	}
+
+	block, err := aes.NewCipher(key())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	return gcm, nil
+}
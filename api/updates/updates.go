@@ -0,0 +1,246 @@
+package updates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gsm/docker"
+	"gsm/registry"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distribution/reference"
+)
+
+const CHECK_TIMEOUT = 5 * time.Minute
+
+// Status is the result of comparing a container's image with its registry
+type Status struct {
+	ContainerID     string    `json:"containerId"`
+	Image           string    `json:"image"`
+	LocalDigest     string    `json:"localDigest"`
+	RemoteDigest    string    `json:"remoteDigest"`
+	UpdateAvailable bool      `json:"updateAvailable"`
+	CheckedAt       time.Time `json:"checkedAt"`
+	Error           string    `json:"error,omitempty"` // Set when the image could not be compared, e.g. a locally built image
+}
+
+// Checker periodically compares the image digests of all containers with the digests their tags point at on the registry
+type Checker struct {
+	cli      docker.Client
+	registry registry.Client
+	interval time.Duration
+
+	mu       sync.RWMutex
+	statuses map[string]Status // By container ID
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func New(cli docker.Client, registryClient registry.Client, interval time.Duration) *Checker {
+	return &Checker{
+		cli:      cli,
+		registry: registryClient,
+		interval: interval,
+		statuses: make(map[string]Status),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start checks all containers now and then every interval, a zero interval disables the background checks
+func (c *Checker) Start() {
+	if c.interval <= 0 {
+		close(c.done)
+		return
+	}
+
+	go func() {
+		defer close(c.done)
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			c.run()
+
+			select {
+			case <-ticker.C:
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background checks and waits for a running check to finish
+func (c *Checker) Stop() {
+	close(c.stop)
+	<-c.done
+}
+
+func (c *Checker) run() {
+	ctx, cancel := context.WithTimeout(context.Background(), CHECK_TIMEOUT)
+	defer cancel()
+
+	if err := c.CheckAll(ctx); err != nil {
+		log.Printf("Image update check failed: %v", err)
+	}
+}
+
+// CheckAll checks every container, statuses of removed containers are dropped
+func (c *Checker) CheckAll(ctx context.Context) error {
+	containers, err := c.cli.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Containers often share an image, look each one up once per run
+	remoteDigests := make(map[string]digestResult)
+	localDigests := make(map[string]digestsResult)
+
+	statuses := make(map[string]Status, len(containers))
+	for _, container := range containers {
+		statuses[container.ID] = c.check(ctx, container, remoteDigests, localDigests)
+	}
+
+	c.mu.Lock()
+	c.statuses = statuses
+	c.mu.Unlock()
+	return nil
+}
+
+// Check checks a single container by ID or name and stores the result
+func (c *Checker) Check(ctx context.Context, id string) (Status, error) {
+	containers, err := c.cli.ListContainers(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+
+	for _, container := range containers {
+		if !matches(container, id) {
+			continue
+		}
+
+		status := c.check(ctx, container, make(map[string]digestResult), make(map[string]digestsResult))
+		c.mu.Lock()
+		c.statuses[container.ID] = status
+		c.mu.Unlock()
+		return status, nil
+	}
+
+	return Status{}, fmt.Errorf("container %s not found", id)
+}
+
+// Status returns the last result for the container ID, ok is false when it has not been checked yet
+func (c *Checker) Status(containerID string) (status Status, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	status, ok = c.statuses[containerID]
+	return status, ok
+}
+
+// Forget drops the result for a container, e.g. once it has been recreated
+func (c *Checker) Forget(containerID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.statuses, containerID)
+}
+
+type digestResult struct {
+	digest string
+	err    error
+}
+
+type digestsResult struct {
+	digests []string
+	err     error
+}
+
+func (c *Checker) check(ctx context.Context, container docker.ContainerListItem, remoteDigests map[string]digestResult, localDigests map[string]digestsResult) Status {
+	status := Status{
+		ContainerID: container.ID,
+		Image:       container.Image,
+		CheckedAt:   time.Now(),
+	}
+
+	// Containers whose image was removed or retagged report the image ID instead of a name
+	if strings.HasPrefix(container.Image, "sha256:") {
+		status.Error = "container image has no tag"
+		return status
+	}
+
+	local, ok := localDigests[container.ImageID]
+	if !ok {
+		local.digests, local.err = c.cli.ImageDigests(ctx, container.ImageID)
+		localDigests[container.ImageID] = local
+	}
+	if local.err != nil {
+		status.Error = local.err.Error()
+		return status
+	}
+
+	status.LocalDigest = repoDigest(container.Image, local.digests)
+	if status.LocalDigest == "" {
+		status.Error = "image was not pulled from a registry"
+		return status
+	}
+
+	remote, ok := remoteDigests[container.Image]
+	if !ok {
+		remote.digest, remote.err = c.registry.Digest(ctx, container.Image)
+		remoteDigests[container.Image] = remote
+	}
+	if remote.err != nil {
+		if errors.Is(remote.err, registry.ErrNotFound) {
+			status.Error = "image not found on registry"
+		} else {
+			status.Error = remote.err.Error()
+		}
+		return status
+	}
+
+	status.RemoteDigest = remote.digest
+	status.UpdateAvailable = status.LocalDigest != status.RemoteDigest
+	return status
+}
+
+// repoDigest returns the digest of the image's repository among the image's repo digests, e.g. "nginx@sha256:..."
+func repoDigest(image string, digests []string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+
+	for _, digest := range digests {
+		canonical, err := reference.ParseNormalizedNamed(digest)
+		if err != nil {
+			continue
+		}
+		if canonical.Name() != named.Name() {
+			continue
+		}
+		if digested, ok := canonical.(reference.Digested); ok {
+			return digested.Digest().String()
+		}
+	}
+	return ""
+}
+
+func matches(container docker.ContainerListItem, id string) bool {
+	if id == "" {
+		return false
+	}
+	if strings.HasPrefix(container.ID, id) {
+		return true
+	}
+	for _, name := range container.Names {
+		if strings.TrimPrefix(name, "/") == id {
+			return true
+		}
+	}
+	return false
+}
@@ -1,5 +1,9 @@
 import { apiClient } from "./config";
-import { UserResponseData, AllowedUserResponseData } from "./types";
+import {
+  UserResponseData,
+  AllowedUserResponseData,
+  AuthProviderResponseData,
+} from "./types";
 
 export const authApi = {
   getCurrentUser: async () => {
@@ -7,6 +11,21 @@ export const authApi = {
     return response.data;
   },
 
+  getProviders: async () => {
+    const response = await apiClient.get<AuthProviderResponseData[]>(
+      "/auth/providers"
+    );
+    return response.data;
+  },
+
+  signInWithPassword: async (
+    provider: string,
+    email: string,
+    password: string
+  ) => {
+    await apiClient.post(`/auth/${provider}/signin`, { email, password });
+  },
+
   getAllowedUsers: async () => {
     const response = await apiClient.get<AllowedUserResponseData[]>("/users/");
     return response.data;
@@ -1,4 +1,8 @@
-import axios, { AxiosInstance, AxiosError } from "axios";
+import axios, {
+  AxiosInstance,
+  AxiosError,
+  InternalAxiosRequestConfig,
+} from "axios";
 import { apiUrl } from "../config/constants";
 
 interface ApiErrorResponse {
@@ -16,15 +20,45 @@ export class ApiError extends Error {
   }
 }
 
+// Shared so that concurrent 401s from requests and streams wait on a single refresh
+let refreshing: Promise<unknown> | null = null;
+
+const refreshSession = (client: AxiosInstance) => {
+  refreshing ??= client
+    .post("/auth/refresh")
+    .finally(() => (refreshing = null));
+  return refreshing;
+};
+
 export const createApiClient = (): AxiosInstance => {
   const client = axios.create({
     baseURL: apiUrl,
     withCredentials: true,
   });
 
+  // Access tokens are short-lived, refresh the session once and retry on 401
   client.interceptors.response.use(
     (response) => response,
-    (error: AxiosError<ApiErrorResponse>) => {
+    async (error: AxiosError<ApiErrorResponse>) => {
+      const request = error.config as
+        | (InternalAxiosRequestConfig & { _retried?: boolean })
+        | undefined;
+      if (
+        error.response?.status === 401 &&
+        request &&
+        !request._retried &&
+        request.url !== "/auth/refresh" &&
+        !request.url?.endsWith("/signin")
+      ) {
+        request._retried = true;
+        try {
+          await refreshSession(client);
+          return client(request);
+        } catch {
+          // Fall through and report the original error
+        }
+      }
+
       if (error.response) {
         throw new ApiError(
           error.response.data?.error || "An error occurred",
@@ -41,18 +75,120 @@ export const createApiClient = (): AxiosInstance => {
 
 export const apiClient = createApiClient();
 
-export function createEventSource<T extends { data: any; type: string }>(
-  url: string
-): EventSource & {
-  onmessage: (event: T) => void;
-  onerror: (event: Event) => void;
-} {
-  const eventSourceInit: EventSourceInit = {
-    withCredentials: true,
+export interface ServerEventSource<
+  T extends { data: any; type: string } = MessageEvent
+> {
+  onmessage: ((event: T) => void) | null;
+  onerror: ((event: Event) => void) | null;
+  close: () => void;
+}
+
+// Opens an EventSource that survives access token expiry. EventSource gives up for good on an error
+// response such as the 401 of an expired token, so the session is refreshed and the stream reopened.
+export function createEventSource<
+  T extends { data: any; type: string } = MessageEvent
+>(url: string): ServerEventSource<T> {
+  let source: EventSource;
+  let closed = false;
+  let refreshed = false;
+
+  const stream: ServerEventSource<T> = {
+    onmessage: null,
+    onerror: null,
+    close: () => {
+      closed = true;
+      source.close();
+    },
   };
 
-  return new EventSource(`${apiUrl}${url}`, eventSourceInit) as EventSource & {
-    onmessage: (event: T) => void;
-    onerror: (event: Event) => void;
+  const open = () => {
+    source = new EventSource(`${apiUrl}${url}`, { withCredentials: true });
+    source.onopen = () => (refreshed = false);
+    source.onmessage = (event) => stream.onmessage?.(event as unknown as T);
+    source.onerror = (event) => {
+      if (source.readyState === EventSource.CLOSED && !refreshed && !closed) {
+        refreshed = true;
+        refreshSession(apiClient).then(
+          () => !closed && open(),
+          () => stream.onerror?.(event)
+        );
+        return;
+      }
+      stream.onerror?.(event);
+    };
   };
+
+  open();
+  return stream;
+}
+
+// fetch with cookies that refreshes the session and retries once when the access token expired
+async function fetchWithRefresh(url: string, init: RequestInit) {
+  const request = () =>
+    fetch(`${apiUrl}${url}`, { ...init, credentials: "include" });
+
+  const response = await request();
+  if (response.status !== 401) {
+    return response;
+  }
+  try {
+    await refreshSession(apiClient);
+  } catch {
+    return response;
+  }
+  return request();
+}
+
+// Posts a JSON body and calls onEvent for each server-sent event of the streamed response,
+// for long running operations that EventSource cannot start because it only sends GET requests
+export async function postEventStream<T>(
+  url: string,
+  body: unknown,
+  onEvent: (event: T) => void
+): Promise<void> {
+  const response = await fetchWithRefresh(url, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(body),
+  });
+  await readEventStream(response, onEvent);
+}
+
+// Like createEventSource but rejects with the error of a failed request, which EventSource cannot read.
+// Aborting the signal stops the stream.
+export async function getEventStream<T>(
+  url: string,
+  onEvent: (event: T) => void,
+  signal?: AbortSignal
+): Promise<void> {
+  const response = await fetchWithRefresh(url, { signal });
+  await readEventStream(response, onEvent);
+}
+
+async function readEventStream<T>(
+  response: Response,
+  onEvent: (event: T) => void
+): Promise<void> {
+  if (!response.ok || !response.body) {
+    const data = await response.json().catch(() => ({}));
+    throw new ApiError(data.error || response.statusText, response.status, data);
+  }
+
+  const reader = response.body.getReader();
+  const decoder = new TextDecoder();
+  let buffer = "";
+  for (;;) {
+    const { done, value } = await reader.read();
+    if (done) break;
+    buffer += decoder.decode(value, { stream: true });
+
+    let end;
+    while ((end = buffer.indexOf("\n\n")) >= 0) {
+      const message = buffer.slice(0, end);
+      buffer = buffer.slice(end + 2);
+      if (message.startsWith("data: ")) {
+        onEvent(JSON.parse(message.slice(6)));
+      }
+    }
+  }
 }
@@ -3,9 +3,13 @@ import {
   ContainerListItemResponseData,
   ContainerDetailsResponseData,
   ContainerImageResponseData,
+  ImageSearchResultResponseData,
+  ImageTagListResponseData,
   CreateContainerRequestData,
   ContainerExecResponseData,
   CreateContainerResponseData,
+  ImageUpdateResponseData,
+  UpgradeContainerResponseData,
 } from "./types";
 
 export const dockerApi = {
@@ -45,6 +49,26 @@ export const dockerApi = {
     return response.data;
   },
 
+  getImageUpdate: async (
+    id: string,
+    refresh = false
+  ): Promise<ImageUpdateResponseData> => {
+    const response = await apiClient.get<ImageUpdateResponseData>(
+      `/docker/containers/${id}/update`,
+      { params: refresh ? { refresh: true } : undefined }
+    );
+    return response.data;
+  },
+
+  upgradeContainer: async (
+    id: string
+  ): Promise<UpgradeContainerResponseData> => {
+    const response = await apiClient.post<UpgradeContainerResponseData>(
+      `/docker/containers/${id}/upgrade`
+    );
+    return response.data;
+  },
+
   startContainer: async (id: string) => {
     await apiClient.post(`/docker/containers/${id}/start`);
   },
@@ -93,6 +117,22 @@ export const dockerApi = {
     );
   },
 
+  searchImages: async (term: string, limit = 10) => {
+    const response = await apiClient.get<ImageSearchResultResponseData[]>(
+      "/docker/images/search",
+      { params: { term, limit } }
+    );
+    return response.data;
+  },
+
+  listImageTags: async (image: string, last?: string) => {
+    const response = await apiClient.get<ImageTagListResponseData>(
+      "/docker/images/tags",
+      { params: { image, last } }
+    );
+    return response.data;
+  },
+
   removeImage: async (id: string) => {
     await apiClient.delete(`/docker/images/${id}`);
   },
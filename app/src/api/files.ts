@@ -1,10 +1,64 @@
-import { apiClient } from "./config";
+import {
+  ApiError,
+  apiClient,
+  getEventStream,
+  postEventStream,
+} from "./config";
 import {
   FileInfoResponseData,
   FileContentResponseData,
   UploadFileResponseData,
+  CreateUploadResponseData,
+  ArchiveFormat,
+  ArchiveProgressEventData,
+  ArchiveSummaryResponseData,
+  SearchFilesParams,
+  SearchResultEventData,
+  SearchSummaryEventData,
 } from "./types";
 
+export const UPLOAD_CHUNK_SIZE = 8 * 1024 * 1024;
+const UPLOAD_RETRIES = 5;
+
+export const isArchive = (name: string) =>
+  /\.(zip|tar|tar\.gz|tgz|tar\.zst|tzst)$/i.test(name);
+
+const archiveQuery = (
+  paths: string[],
+  excludes: string[],
+  format: ArchiveFormat
+) => {
+  const params = new URLSearchParams({ format });
+  paths.forEach((path) => params.append("path", path));
+  excludes.forEach((exclude) => params.append("exclude", exclude));
+  return params;
+};
+
+// Runs an extract or compress request, resolving with the final event or throwing its error
+const runArchiveOperation = async (
+  url: string,
+  body: unknown,
+  onProgress?: (event: ArchiveProgressEventData) => void
+) => {
+  let result: ArchiveProgressEventData | undefined;
+  await postEventStream<ArchiveProgressEventData>(url, body, (event) => {
+    if (event.error) {
+      throw new ApiError(event.error);
+    }
+    if (event.done) {
+      result = event;
+    } else {
+      onProgress?.(event);
+    }
+  });
+  if (!result) {
+    throw new ApiError("Archive operation ended unexpectedly");
+  }
+  return result;
+};
+
+const wait = (ms: number) => new Promise((resolve) => setTimeout(resolve, ms));
+
 export const filesApi = {
   list: async (path?: string) => {
     const response = await apiClient.get<FileInfoResponseData[]>("/files/", {
@@ -54,6 +108,52 @@ export const filesApi = {
     return response.data;
   },
 
+  // Sends the file in chunks, resuming from the offset the server reports after a failed chunk
+  uploadResumable: async (
+    path: string,
+    file: File,
+    onProgress?: (uploaded: number, total: number) => void
+  ) => {
+    const { data: upload } = await apiClient.post<CreateUploadResponseData>(
+      "/files/uploads",
+      { path, filename: file.name, size: file.size }
+    );
+    const url = `/files/uploads/${encodeURIComponent(upload.id)}`;
+
+    let offset = 0;
+    let failures = 0;
+    while (offset < file.size) {
+      const chunk = file.slice(offset, offset + UPLOAD_CHUNK_SIZE);
+      try {
+        const response = await apiClient.patch(url, chunk, {
+          headers: {
+            "Content-Type": "application/offset+octet-stream",
+            "Upload-Offset": String(offset),
+          },
+        });
+        offset = Number(response.headers["upload-offset"]);
+        failures = 0;
+        onProgress?.(offset, file.size);
+      } catch (err) {
+        if (++failures > UPLOAD_RETRIES) {
+          throw err;
+        }
+        await wait(1000 * failures);
+        const response = await apiClient.head(url);
+        offset = Number(response.headers["upload-offset"]);
+      }
+    }
+
+    const response = await apiClient.post<UploadFileResponseData>(
+      `${url}/finalize`
+    );
+    return response.data;
+  },
+
+  cancelUpload: async (id: string) => {
+    await apiClient.delete(`/files/uploads/${encodeURIComponent(id)}`);
+  },
+
   uploadDirectory: async (path: string, files: FileList) => {
     const formData = new FormData();
     Array.from(files).forEach((file) => {
@@ -73,6 +173,55 @@ export const filesApi = {
     return response.data;
   },
 
+  extract: (
+    source: string,
+    destination: string,
+    onProgress?: (event: ArchiveProgressEventData) => void
+  ) => runArchiveOperation("/files/extract", { source, destination }, onProgress),
+
+  compress: (
+    paths: string[],
+    destination: string,
+    format?: ArchiveFormat,
+    onProgress?: (event: ArchiveProgressEventData) => void
+  ) =>
+    runArchiveOperation(
+      "/files/compress",
+      { paths, destination, format },
+      onProgress
+    ),
+
+  // Streams matching files to onResult, resolving with the summary once the search ends
+  search: async (
+    params: SearchFilesParams,
+    onResult: (result: SearchResultEventData) => void,
+    signal?: AbortSignal
+  ) => {
+    const query = new URLSearchParams();
+    Object.entries(params).forEach(([key, value]) => {
+      if (value !== undefined && value !== "") {
+        query.set(key, String(value));
+      }
+    });
+
+    let summary: SearchSummaryEventData | undefined;
+    await getEventStream<SearchResultEventData & SearchSummaryEventData>(
+      `/files/search?${query}`,
+      (event) => {
+        if (event.error) {
+          throw new ApiError(event.error);
+        }
+        if (event.done) {
+          summary = event;
+        } else {
+          onResult(event);
+        }
+      },
+      signal
+    );
+    return summary;
+  },
+
   download: (path: string) => {
     window.open(
       `${apiClient.defaults.baseURL}/files/download?path=${encodeURIComponent(
@@ -80,4 +229,32 @@ export const filesApi = {
       )}`
     );
   },
+
+  // Reports the number of files and uncompressed size a downloadArchive call would produce
+  summarizeArchive: async (
+    paths: string[],
+    excludes: string[] = [],
+    format: ArchiveFormat = "zip"
+  ): Promise<ArchiveSummaryResponseData> => {
+    const params = archiveQuery(paths, excludes, format);
+    params.set("dryRun", "true");
+    const response = await apiClient.get<ArchiveSummaryResponseData>(
+      `/files/download/archive?${params}`
+    );
+    return response.data;
+  },
+
+  downloadArchive: (
+    paths: string[],
+    excludes: string[] = [],
+    format: ArchiveFormat = "zip"
+  ) => {
+    window.open(
+      `${apiClient.defaults.baseURL}/files/download/archive?${archiveQuery(
+        paths,
+        excludes,
+        format
+      )}`
+    );
+  },
 };
@@ -2,6 +2,7 @@ import { dockerApi as docker } from "./docker";
 import { filesApi as files } from "./files";
 import { authApi as auth } from "./auth";
 import { systemApi as system } from "./system";
+import { templatesApi as templates } from "./templates";
 
 export * from "./types";
 
@@ -10,4 +11,5 @@ export const api = {
   files,
   auth,
   system,
+  templates,
 } as const;
@@ -0,0 +1,34 @@
+import { apiClient } from "./config";
+import {
+  ContainerTemplateRequestData,
+  ContainerTemplateResponseData,
+} from "./types";
+
+export const templatesApi = {
+  listTemplates: async () => {
+    const response = await apiClient.get<ContainerTemplateResponseData[]>(
+      "/templates/"
+    );
+    return response.data;
+  },
+
+  createTemplate: async (data: ContainerTemplateRequestData) => {
+    const response = await apiClient.post<ContainerTemplateResponseData>(
+      "/templates/",
+      data
+    );
+    return response.data;
+  },
+
+  updateTemplate: async (id: number, data: ContainerTemplateRequestData) => {
+    const response = await apiClient.put<ContainerTemplateResponseData>(
+      `/templates/${id}`,
+      data
+    );
+    return response.data;
+  },
+
+  deleteTemplate: async (id: number) => {
+    await apiClient.delete(`/templates/${id}`);
+  },
+};
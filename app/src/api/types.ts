@@ -4,6 +4,12 @@ export interface UserResponseData {
   picture?: string;
 }
 
+export interface AuthProviderResponseData {
+  name: string;
+  displayName: string;
+  type: "oauth" | "password";
+}
+
 export interface AuthResponseData {
   user: UserResponseData;
 }
@@ -12,6 +18,7 @@ export interface AllowedUserResponseData extends UserResponseData {
   Id: number;
   CreatedAt: string;
   UpdatedAt: string;
+  managedBy?: string;
 }
 
 export interface CreateContainerRequestData {
@@ -27,6 +34,31 @@ export interface CreateContainerRequestData {
   attachStdin: boolean;
   attachStdout: boolean;
   attachStderr: boolean;
+  healthcheck?: ContainerHealthcheckData;
+}
+
+export interface ContainerTemplateRequestData {
+  name: string;
+  description?: string;
+  config: CreateContainerRequestData;
+}
+
+export interface ContainerTemplateResponseData {
+  ID: number;
+  CreatedAt: string;
+  UpdatedAt: string;
+  name: string;
+  description: string;
+  config: CreateContainerRequestData;
+  createdBy: string;
+}
+
+export interface ContainerHealthcheckData {
+  test: string[];
+  interval: number;
+  timeout: number;
+  retries: number;
+  startPeriod: number;
 }
 
 export interface CreateContainerResponseData {
@@ -55,6 +87,65 @@ export interface UploadFileResponseData {
   path: string;
 }
 
+export type ArchiveFormat = "zip" | "tar" | "tar.gz" | "tar.zst";
+
+export interface ArchiveSummaryResponseData {
+  files: number;
+  directories: number;
+  size: number;
+}
+
+export interface SearchFilesParams {
+  path?: string;
+  name?: string;
+  minSize?: number;
+  maxSize?: number;
+  modifiedAfter?: string;
+  modifiedBefore?: string;
+  content?: string;
+  regex?: boolean;
+  ignoreCase?: boolean;
+  limit?: number;
+}
+
+export interface SearchLineMatch {
+  line: number;
+  text: string;
+}
+
+export interface SearchResultEventData {
+  path: string;
+  name: string;
+  size: number;
+  isDir: boolean;
+  modTime: string;
+  matches?: SearchLineMatch[];
+}
+
+export interface SearchSummaryEventData {
+  files?: number;
+  matches?: number;
+  truncated?: boolean;
+  timedOut?: boolean;
+  done?: boolean;
+  error?: string;
+}
+
+export interface ArchiveProgressEventData {
+  entry?: string;
+  entries?: number;
+  bytes?: number;
+  skipped?: boolean;
+  done?: boolean;
+  error?: string;
+}
+
+export interface CreateUploadResponseData {
+  id: string;
+  offset: number;
+  size: number;
+}
+
 export interface FileListResponseData {
   files: FileInfoResponseData[];
 }
@@ -63,8 +154,24 @@ export interface ContainerListItemResponseData {
   id: string;
   names: string[];
   image: string;
+  imageId: string;
   state: string;
   status: string;
+  updateAvailable: boolean;
+}
+
+export interface ImageUpdateResponseData {
+  containerId: string;
+  image: string;
+  localDigest: string;
+  remoteDigest: string;
+  updateAvailable: boolean;
+  checkedAt: string;
+  error?: string;
+}
+
+export interface UpgradeContainerResponseData extends CreateContainerResponseData {
+  update?: ImageUpdateResponseData;
 }
 
 export interface ContainerStateResponseData {
@@ -72,6 +179,18 @@ export interface ContainerStateResponseData {
   running: boolean;
   startedAt: string;
   finishedAt: string;
+  health?: ContainerHealthResponseData;
+}
+
+export interface ContainerHealthResponseData {
+  status: "starting" | "healthy" | "unhealthy";
+  failingStreak: number;
+  log: {
+    start: string;
+    end: string;
+    exitCode: number;
+    output: string;
+  }[];
 }
 
 export interface ContainerConfigResponseData {
@@ -81,6 +200,7 @@ export interface ContainerConfigResponseData {
   attachStderr: boolean;
   env: string[];
   image: string;
+  healthcheck?: ContainerHealthcheckData;
 }
 
 export interface ContainerDetailsResponseData {
@@ -97,6 +217,18 @@ export interface ContainerDetailsResponseData {
     memory: number;
     cpu: number;
   };
+  query?: ServerQueryResponseData;
+}
+
+export interface ServerQueryResponseData {
+  protocol: "a2s" | "minecraft";
+  online: boolean;
+  name: string;
+  map: string;
+  version: string;
+  players: number;
+  maxPlayers: number;
+  error?: string;
 }
 
 export interface ContainerMountResponseData {
@@ -119,6 +251,26 @@ export interface ContainerImageResponseData {
   Containers: number;
 }
 
+export interface ImageSearchResultResponseData {
+  name: string;
+  description: string;
+  star_count: number;
+  is_official: boolean;
+  is_automated: boolean;
+}
+
+export interface ImageTagResponseData {
+  name: string;
+  digest: string;
+  size: number;
+}
+
+export interface ImageTagListResponseData {
+  repository: string;
+  tags: ImageTagResponseData[];
+  next?: string;
+}
+
 export interface PullProgressResponseData {
   status: string;
   progressDetail?: {
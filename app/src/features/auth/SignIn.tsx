@@ -1,12 +1,43 @@
-import React from "react";
+import React, { useEffect, useState } from "react";
 import { TbCubeSpark } from "react-icons/tb";
+import { api, AuthProviderResponseData } from "../../api";
 import { apiClient } from "../../api/config";
 
 const SignIn: React.FC = () => {
-  const handleSignIn = () => {
-    window.location.href = `${apiClient.defaults.baseURL}/auth/signin`;
+  const [providers, setProviders] = useState<AuthProviderResponseData[]>([]);
+  const [email, setEmail] = useState("");
+  const [password, setPassword] = useState("");
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    api.auth
+      .getProviders()
+      .then(setProviders)
+      .catch((err) => setError(err.message));
+  }, []);
+
+  const handleSignIn = (provider: string) => {
+    window.location.href = `${apiClient.defaults.baseURL}/auth/${provider}/signin`;
+  };
+
+  const handlePasswordSignIn = async (
+    e: React.FormEvent,
+    provider: string
+  ) => {
+    e.preventDefault();
+    setError(null);
+    try {
+      await api.auth.signInWithPassword(provider, email, password);
+      window.location.reload();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Sign in failed");
+    }
   };
 
+  const buttonClassName = `bg-gray-800 hover:bg-gray-700 text-white font-semibold py-2 px-4 rounded 
+                     focus:outline-none focus:ring-2 focus:ring-blue-500 focus:ring-opacity-50
+                     transition duration-200`;
+
   return (
     <div className="page-container py-10 px-5">
       <TbCubeSpark
@@ -22,14 +53,46 @@ const SignIn: React.FC = () => {
           <p className="text-lg md:text-xl text-gray-300 mb-6">
             You have to sign in to access the dashboard.
           </p>
-          <button
-            onClick={handleSignIn}
-            className="bg-gray-800 hover:bg-gray-700 text-white font-semibold py-2 px-4 rounded 
-                     focus:outline-none focus:ring-2 focus:ring-blue-500 focus:ring-opacity-50
-                     transition duration-200"
-          >
-            Sign in with Google
-          </button>
+          <div className="flex flex-col gap-3 max-w-xs">
+            {providers.map((provider) =>
+              provider.type === "password" ? (
+                <form
+                  key={provider.name}
+                  onSubmit={(e) => handlePasswordSignIn(e, provider.name)}
+                  className="flex flex-col gap-2"
+                >
+                  <input
+                    type="email"
+                    placeholder="Email"
+                    value={email}
+                    onChange={(e) => setEmail(e.target.value)}
+                    className="bg-gray-900 border border-gray-700 rounded py-2 px-3"
+                    required
+                  />
+                  <input
+                    type="password"
+                    placeholder="Password"
+                    value={password}
+                    onChange={(e) => setPassword(e.target.value)}
+                    className="bg-gray-900 border border-gray-700 rounded py-2 px-3"
+                    required
+                  />
+                  <button type="submit" className={buttonClassName}>
+                    Sign in
+                  </button>
+                </form>
+              ) : (
+                <button
+                  key={provider.name}
+                  onClick={() => handleSignIn(provider.name)}
+                  className={buttonClassName}
+                >
+                  Sign in with {provider.displayName}
+                </button>
+              )
+            )}
+            {error && <p className="text-red-400">{error}</p>}
+          </div>
         </div>
       </div>
     </div>
@@ -102,11 +102,50 @@ export default function Container() {
               >
                 {capitalizeFirstLetter(container.state.status || "unknown")}
               </span>
+              {container.state.running && container.state.health && (
+                <span
+                  className={`px-2 py-1 text-xs rounded whitespace-nowrap ${
+                    container.state.health.status === "healthy"
+                      ? "bg-green-900 text-green-100"
+                      : container.state.health.status === "unhealthy"
+                      ? "bg-red-900 text-red-100"
+                      : "bg-yellow-900 text-yellow-100"
+                  }`}
+                  title={
+                    container.state.health.log[
+                      container.state.health.log.length - 1
+                    ]?.output
+                  }
+                >
+                  {capitalizeFirstLetter(container.state.health.status)}
+                </span>
+              )}
+              {container.state.running && container.query && (
+                <span
+                  className={`px-2 py-1 text-xs rounded whitespace-nowrap ${
+                    container.query.online
+                      ? "bg-blue-900 text-blue-100"
+                      : "bg-gray-700 text-gray-300"
+                  }`}
+                  title={container.query.error || container.query.name}
+                >
+                  {container.query.online
+                    ? `${container.query.players}/${container.query.maxPlayers} players`
+                    : "Not responding"}
+                </span>
+              )}
             </div>
             <div className="flex flex-col space-y-1">
               <span className="text-sm text-gray-400">
                 {container.config.image}
               </span>
+              {container.state.running && container.query?.online && (
+                <span className="text-xs text-gray-500">
+                  {[container.query.map, container.query.version]
+                    .filter(Boolean)
+                    .join(" · ")}
+                </span>
+              )}
               <span className="text-xs text-gray-500">
                 {container.state.running
                   ? `Started ${formatDate(new Date(container.state.startedAt))}`
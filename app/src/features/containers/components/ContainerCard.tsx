@@ -25,6 +25,11 @@ export function ContainerCard({
             {getContainerName(container)}
           </div>
           <div className="flex items-center gap-2">
+            {container.updateAvailable && (
+              <span className="text-xs px-2 py-0.5 rounded bg-blue-900 text-blue-300">
+                Update available
+              </span>
+            )}
             <ContainerStatusLabel state={container.state} />
           </div>
         </div>
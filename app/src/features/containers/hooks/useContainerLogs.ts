@@ -1,12 +1,13 @@
 import { useState, useRef, useEffect } from "react";
 import { api } from "../../../api";
+import { ServerEventSource } from "../../../api/config";
 import { useToast } from "../../../hooks/useToast";
 
 export function useContainerLogs(id: string | undefined) {
   const toast = useToast();
   const [logs, setLogs] = useState<string[]>([]);
   const [command, setCommand] = useState("");
-  const logEventSourceRef = useRef<EventSource | null>(null);
+  const logEventSourceRef = useRef<ServerEventSource | null>(null);
   const logContainerRef = useRef<HTMLDivElement | null>(null);
 
   const fetchLogs = async () => {
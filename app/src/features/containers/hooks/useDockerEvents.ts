@@ -1,8 +1,9 @@
 import { useRef } from "react";
 import { api } from "../../../api";
+import { ServerEventSource } from "../../../api/config";
 
 export function useDockerEvents(onContainerEvent: () => void) {
-  const dockerEventSourceRef = useRef<EventSource | null>(null);
+  const dockerEventSourceRef = useRef<ServerEventSource | null>(null);
 
   const connectToDockerEvents = () => {
     if (!dockerEventSourceRef.current) {
@@ -13,7 +14,7 @@ export function useDockerEvents(onContainerEvent: () => void) {
 
         // Check if this event is for a container
         if (eventData.event_type === "container") {
-          if (["start", "die"].includes(eventData.action)) {
+          if (["start", "die", "health_status"].includes(eventData.action)) {
             onContainerEvent();
           }
         }
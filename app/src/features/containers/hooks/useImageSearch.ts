@@ -74,7 +74,7 @@ export function useImageSearch() {
       }
 
       // Debounce the search to prevent too many state updates
-      searchTimeoutRef.current = setTimeout(() => {
+      searchTimeoutRef.current = setTimeout(async () => {
         const searchTerm = value.toLowerCase();
         const filtered = images
           .flatMap((img) => img.RepoTags)
@@ -88,6 +88,22 @@ export function useImageSearch() {
 
         setFilteredImages(filtered);
         setShowImageDropdown(true);
+
+        // Fill up with images from the registry that are not pulled yet
+        if (filtered.length >= 10) {
+          return;
+        }
+        try {
+          const results = await api.docker.searchImages(value.trim());
+          const remote = results
+            .map((result) => result.name)
+            .filter(
+              (name) => !filtered.some((tag) => tag.split(":")[0] === name)
+            );
+          setFilteredImages([...filtered, ...remote].slice(0, 10));
+        } catch (err) {
+          console.error("Failed to search images", err);
+        }
       }, 300);
     },
     [images]
@@ -19,6 +19,7 @@ export function Files() {
     createDirectory,
     deleteFile,
     uploadFiles,
+    extractArchive,
     readFile,
     writeFile,
     navigateToParent,
@@ -173,6 +174,7 @@ export function Files() {
         onFileClick={handleFileClick}
         onDownload={(file) => api.files.download(file.path)}
         onDelete={handleDelete}
+        onExtract={extractArchive}
       />
 
       {/* New Folder Dialog */}
@@ -1,5 +1,13 @@
 import { FileInfoResponseData } from "../../../api";
-import { TbFile, TbFolder, TbDownload, TbTrash, TbEdit } from "react-icons/tb";
+import {
+  TbFile,
+  TbFolder,
+  TbDownload,
+  TbTrash,
+  TbEdit,
+  TbFileZip,
+} from "react-icons/tb";
+import { isArchive } from "../../../api/files";
 import Button from "../../../components/ui/Button";
 import { formatBytes, formatDate } from "../../../utils/format";
 
@@ -8,6 +16,7 @@ interface FileCardProps {
   onFileClick: (file: FileInfoResponseData) => void;
   onDownload: (file: FileInfoResponseData) => void;
   onDelete: (file: FileInfoResponseData) => void;
+  onExtract: (file: FileInfoResponseData) => void;
 }
 
 export default function FileCard({
@@ -15,6 +24,7 @@ export default function FileCard({
   onFileClick,
   onDownload,
   onDelete,
+  onExtract,
 }: FileCardProps) {
   return (
     <div className="bg-gray-800 rounded-lg p-4 mb-4">
@@ -54,6 +64,14 @@ export default function FileCard({
             title={file.isDir ? "Download as ZIP" : "Download"}
           />
         )}
+        {!file.isDir && isArchive(file.name) && (
+          <Button
+            onClick={() => onExtract(file)}
+            icon={<TbFileZip className="h-4 w-4" />}
+            className="p-1 hover:text-yellow-500"
+            title="Extract here"
+          />
+        )}
         {!file.isDir && file.isWritable && (
           <Button
             onClick={() => onFileClick(file)}
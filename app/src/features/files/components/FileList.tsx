@@ -1,5 +1,13 @@
 import { FileInfoResponseData } from "../../../api";
-import { TbFile, TbFolder, TbDownload, TbTrash, TbEdit } from "react-icons/tb";
+import {
+  TbFile,
+  TbFolder,
+  TbDownload,
+  TbTrash,
+  TbEdit,
+  TbFileZip,
+} from "react-icons/tb";
+import { isArchive } from "../../../api/files";
 import Button from "../../../components/ui/Button";
 import { formatBytes, formatDate } from "../../../utils/format";
 import FileCard from "./FileCard";
@@ -10,6 +18,7 @@ interface FileListProps {
   onFileClick: (file: FileInfoResponseData) => void;
   onDownload: (file: FileInfoResponseData) => void;
   onDelete: (file: FileInfoResponseData) => void;
+  onExtract: (file: FileInfoResponseData) => void;
 }
 
 export function FileList({
@@ -18,6 +27,7 @@ export function FileList({
   onFileClick,
   onDownload,
   onDelete,
+  onExtract,
 }: FileListProps) {
   if (isLoading) {
     return <div className="text-center py-4">Loading...</div>;
@@ -37,6 +47,7 @@ export function FileList({
           onFileClick={onFileClick}
           onDownload={onDownload}
           onDelete={onDelete}
+          onExtract={onExtract}
         />
       ))}
     </div>
@@ -99,6 +110,14 @@ export function FileList({
                           title={file.isDir ? "Download as ZIP" : "Download"}
                         />
                       )}
+                      {!file.isDir && isArchive(file.name) && (
+                        <Button
+                          onClick={() => onExtract(file)}
+                          icon={<TbFileZip className="h-4 w-4" />}
+                          className="p-1 hover:text-yellow-500"
+                          title="Extract here"
+                        />
+                      )}
                       {!file.isDir && file.isWritable && (
                         <Button
                           onClick={() => onFileClick(file)}
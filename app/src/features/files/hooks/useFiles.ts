@@ -1,6 +1,7 @@
 import { useState, useEffect, useCallback, useRef } from "react";
 import { FileInfoResponseData } from "../../../api";
 import { api } from "../../../api";
+import { UPLOAD_CHUNK_SIZE } from "../../../api/files";
 import { useToast } from "../../../hooks/useToast";
 
 export function useFiles() {
@@ -79,7 +80,12 @@ export function useFiles() {
         const path = currentPath === "" ? "/" : currentPath;
         const fileArray = Array.from(files);
         for (const file of fileArray) {
-          await api.files.upload(path, file);
+          // Large files are uploaded in chunks so a dropped connection does not start over
+          if (file.size > UPLOAD_CHUNK_SIZE) {
+            await api.files.uploadResumable(path, file);
+          } else {
+            await api.files.upload(path, file);
+          }
         }
         await fetchFiles();
         toastRef.current.success(
@@ -96,6 +102,22 @@ export function useFiles() {
     [currentPath, fetchFiles]
   );
 
+  const extractArchive = useCallback(
+    async (file: FileInfoResponseData) => {
+      try {
+        const destination = currentPath === "" ? "/" : currentPath;
+        const result = await api.files.extract(file.path, destination);
+        await fetchFiles();
+        toastRef.current.success(`Extracted ${result.entries ?? 0} entries`);
+        return true;
+      } catch (err: any) {
+        toastRef.current.error(err.message || "Failed to extract archive");
+        return false;
+      }
+    },
+    [currentPath, fetchFiles]
+  );
+
   const readFile = useCallback(async (file: FileInfoResponseData) => {
     try {
       const data = await api.files.getContent(file.path);
@@ -134,6 +156,7 @@ export function useFiles() {
     createDirectory,
     deleteFile,
     uploadFiles,
+    extractArchive,
     readFile,
     writeFile,
     navigateToParent,
@@ -4,6 +4,7 @@ import {
   ContainerImageResponseData,
   PullProgressResponseData,
 } from "../../../api";
+import { ServerEventSource } from "../../../api/config";
 import { useToast } from "../../../hooks/useToast";
 
 export function useImages() {
@@ -16,7 +17,7 @@ export function useImages() {
   const [pullProgress, setPullProgress] = useState<{
     [key: string]: PullProgressResponseData;
   }>({});
-  const eventSourceRef = useRef<EventSource | null>(null);
+  const eventSourceRef = useRef<ServerEventSource | null>(null);
 
   const fetchImages = async () => {
     try {
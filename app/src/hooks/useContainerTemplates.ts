@@ -1,76 +1,131 @@
 import { useState, useEffect } from "react";
+import { api, ContainerTemplateResponseData } from "../api";
 import { ContainerTemplate, TemplateStore } from "../types/docker";
+import {
+  fromCreateContainerRequest,
+  toCreateContainerRequest,
+} from "../utils/containerTemplate";
 import { useToast } from "./useToast";
 
+// Key under which templates were kept in the browser before they were stored by the API
+const LEGACY_STORAGE_KEY = "containerTemplates";
+
 export function useContainerTemplates() {
-  const [templates, setTemplates] = useState<TemplateStore>({});
+  const [templates, setTemplates] = useState<ContainerTemplateResponseData[]>(
+    []
+  );
   const [selectedTemplate, setSelectedTemplate] = useState<string>("");
   const toast = useToast();
 
-  useEffect(() => {
-    // Load templates from localStorage on hook mount
-    const savedTemplates = localStorage.getItem("containerTemplates");
-    if (savedTemplates) {
-      setTemplates(JSON.parse(savedTemplates));
+  const fetchTemplates = async () => {
+    try {
+      setTemplates(await api.templates.listTemplates());
+    } catch (err: any) {
+      toast.error(err.message || "Failed to fetch templates");
+    }
+  };
+
+  // Uploads templates left in this browser's localStorage so they are shared with everyone
+  const importLegacyTemplates = async () => {
+    const savedTemplates = localStorage.getItem(LEGACY_STORAGE_KEY);
+    if (!savedTemplates) return;
+
+    const legacy: TemplateStore = JSON.parse(savedTemplates);
+    const failed: TemplateStore = {};
+    for (const [name, formData] of Object.entries(legacy)) {
+      try {
+        await api.templates.createTemplate({
+          name,
+          config: toCreateContainerRequest(formData),
+        });
+      } catch (err: any) {
+        // A template with the same name already exists on the server, keep that one
+        if (err.statusCode !== 409) {
+          failed[name] = formData;
+        }
+      }
+    }
+
+    if (Object.keys(failed).length > 0) {
+      localStorage.setItem(LEGACY_STORAGE_KEY, JSON.stringify(failed));
+      toast.error(
+        `Failed to import templates: ${Object.keys(failed).join(", ")}`
+      );
+    } else {
+      localStorage.removeItem(LEGACY_STORAGE_KEY);
     }
+  };
+
+  useEffect(() => {
+    importLegacyTemplates().finally(fetchTemplates);
   }, []);
 
-  const saveTemplate = (templateName: string, formData: ContainerTemplate) => {
-    const updatedTemplates = {
-      ...templates,
-      [templateName]: formData,
-    };
+  const findSelected = () =>
+    templates.find((template) => template.name === selectedTemplate);
 
-    setTemplates(updatedTemplates);
-    localStorage.setItem(
-      "containerTemplates",
-      JSON.stringify(updatedTemplates)
-    );
-    toast.success("Template saved successfully");
-    return updatedTemplates;
+  const saveTemplate = async (
+    templateName: string,
+    formData: ContainerTemplate
+  ) => {
+    try {
+      await api.templates.createTemplate({
+        name: templateName,
+        config: toCreateContainerRequest(formData),
+      });
+      await fetchTemplates();
+      setSelectedTemplate(templateName);
+      toast.success("Template saved successfully");
+    } catch (err: any) {
+      toast.error(err.message || "Failed to save template");
+    }
   };
 
-  const updateTemplate = (formData: ContainerTemplate) => {
-    if (!selectedTemplate) {
+  const updateTemplate = async (formData: ContainerTemplate) => {
+    const template = findSelected();
+    if (!template) {
       toast.error("No template selected");
       return;
     }
 
-    const updatedTemplates = {
-      ...templates,
-      [selectedTemplate]: formData,
-    };
-
-    setTemplates(updatedTemplates);
-    localStorage.setItem(
-      "containerTemplates",
-      JSON.stringify(updatedTemplates)
-    );
-    toast.success("Template updated successfully");
+    try {
+      await api.templates.updateTemplate(template.ID, {
+        name: template.name,
+        description: template.description,
+        config: toCreateContainerRequest(formData),
+      });
+      await fetchTemplates();
+      toast.success("Template updated successfully");
+    } catch (err: any) {
+      toast.error(err.message || "Failed to update template");
+    }
   };
 
-  const deleteTemplate = () => {
-    if (!selectedTemplate) {
+  const deleteTemplate = async () => {
+    const template = findSelected();
+    if (!template) {
       toast.error("No template selected");
       return;
     }
 
-    const { [selectedTemplate]: _, ...updatedTemplates } = templates;
-    setTemplates(updatedTemplates);
-    localStorage.setItem(
-      "containerTemplates",
-      JSON.stringify(updatedTemplates)
-    );
-    setSelectedTemplate("");
-    toast.success("Template deleted successfully");
+    try {
+      await api.templates.deleteTemplate(template.ID);
+      await fetchTemplates();
+      setSelectedTemplate("");
+      toast.success("Template deleted successfully");
+    } catch (err: any) {
+      toast.error(err.message || "Failed to delete template");
+    }
   };
 
   const loadTemplate = (templateName: string): ContainerTemplate | null => {
-    return templates[templateName] || null;
+    const template = templates.find(
+      (template) => template.name === templateName
+    );
+    return template ? fromCreateContainerRequest(template.config) : null;
   };
 
   const getTemplateNames = (): string[] => {
-    return Object.keys(templates);
+    return templates.map((template) => template.name);
   };
 
   return {
@@ -1,11 +1,12 @@
 import { useState, useRef, useEffect } from "react";
 import { api } from "../api";
+import { ServerEventSource } from "../api/config";
 import { SystemResources } from "../types/system";
 
 export function useSystemResources() {
   const [resources, setResources] = useState<SystemResources | null>(null);
   const [error, setError] = useState<string | null>(null);
-  const eventSourceRef = useRef<EventSource | null>(null);
+  const eventSourceRef = useRef<ServerEventSource | null>(null);
 
   const connectToResourceStream = () => {
     if (!eventSourceRef.current) {
@@ -0,0 +1,50 @@
+import { CreateContainerRequestData } from "../api";
+import { ContainerPort, ContainerTemplate } from "../types/docker";
+
+// Converts the container form into the payload of a create request, which is also what templates store
+export const toCreateContainerRequest = (
+  formData: ContainerTemplate
+): CreateContainerRequestData => ({
+  ...formData,
+  name: formData.containerName,
+  ports: formData.ports.map((port: ContainerPort) => ({
+    hostPort: parseInt(port.hostPort),
+    containerPort: parseInt(port.containerPort),
+    protocol: port.protocol,
+  })),
+  env: formData.environment
+    .filter((env: { key: string; value: string }) => env.key && env.value)
+    .map((env: { key: string; value: string }) => `${env.key}=${env.value}`),
+  volumes: formData.volumes,
+  memory: formData.memory ? parseInt(formData.memory) : 0,
+  cpu: formData.cpu ? parseFloat(formData.cpu) : 0,
+});
+
+// Converts a stored create request back into the container form, keeping one empty row per list
+export const fromCreateContainerRequest = (
+  config: CreateContainerRequestData
+): ContainerTemplate => ({
+  containerName: config.name,
+  image: config.image,
+  ports: config.ports?.length
+    ? config.ports.map((port) => ({
+        hostPort: String(port.hostPort),
+        containerPort: String(port.containerPort),
+        protocol: port.protocol,
+      }))
+    : [{ containerPort: "", hostPort: "", protocol: "tcp" }],
+  environment: config.env?.length
+    ? config.env.map((env) => {
+        const [key, ...value] = env.split("=");
+        return { key, value: value.join("=") };
+      })
+    : [{ key: "", value: "" }],
+  volumes: config.volumes?.length ? config.volumes : [""],
+  memory: config.memory ? String(config.memory) : "",
+  cpu: config.cpu ? String(config.cpu) : "",
+  restart: config.restart || "no",
+  tty: config.tty,
+  attachStdin: config.attachStdin,
+  attachStdout: config.attachStdout,
+  attachStderr: config.attachStderr,
+});